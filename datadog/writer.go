@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"github.com/cultureamp/glamplify/env"
+	systemLog "log"
 	"net/http"
 	"os"
 	"sync"
@@ -69,6 +70,10 @@ func NewDataDogWriter(configure ...func(*DDFieldWriter)) DDWriter { // https://d
 	for _, config := range configure {
 		config(writer)
 	}
+	if _, err := log.ParseLevel(writer.Level); err != nil {
+		systemLog.Printf("%s, using %s", err.Error(), log.InfoSev)
+		writer.Level = log.InfoSev
+	}
 
 	return writer
 }
@@ -51,10 +51,48 @@ const (
 	// *** Log Environment Variables ***
 	// Level            = "LOG_LEVEL"
 	LogLevel = "LOG_LEVEL"
+	// LogLevelRules    = "LOG_LEVEL_RULES"
+	LogLevelRules = "LOG_LEVEL_RULES"
+	// LogDebugTargets  = "LOG_DEBUG_TARGETS"
+	LogDebugTargets = "LOG_DEBUG_TARGETS"
 	// OmitEmpty        = "LOG_OMITEMPTY"
 	LogOmitEmpty = "LOG_OMITEMPTY"
 	// UseColours       = "LOG_COLOURS"
 	LogUseColours = "LOG_COLOURS"
+	// LogFormat        = "LOG_FORMAT"
+	LogFormat = "LOG_FORMAT"
+	// LogRedact        = "LOG_REDACT"
+	LogRedact = "LOG_REDACT"
+	// LogRedactKeys    = "LOG_REDACT_KEYS"
+	LogRedactKeys = "LOG_REDACT_KEYS"
+	// LogAsyncQueueSize = "LOG_ASYNC_QUEUE_SIZE"
+	LogAsyncQueueSize = "LOG_ASYNC_QUEUE_SIZE"
+	// LogRequestBufferSize = "LOG_REQUEST_BUFFER_SIZE"
+	LogRequestBufferSize = "LOG_REQUEST_BUFFER_SIZE"
+	// LogAuditKey = "LOG_AUDIT_KEY"
+	LogAuditKey = "LOG_AUDIT_KEY"
+	// LogAuditCheckpointEvery = "LOG_AUDIT_CHECKPOINT_EVERY"
+	LogAuditCheckpointEvery = "LOG_AUDIT_CHECKPOINT_EVERY"
+	// LogMetricNamespace = "LOG_METRIC_NAMESPACE"
+	LogMetricNamespace = "LOG_METRIC_NAMESPACE"
+	// LogDedupeWindowInSec = "LOG_DEDUPE_WINDOW_IN_SEC"
+	LogDedupeWindowInSec = "LOG_DEDUPE_WINDOW_IN_SEC"
+
+	// *** OpenTelemetry Environment Variables ***
+	// OtelExporterEndpoint     = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OtelExporterEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// OtelExporterLogsEndpoint = "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"
+	OtelExporterLogsEndpoint = "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"
+	// OtelExporterHeaders      = "OTEL_EXPORTER_OTLP_HEADERS"
+	OtelExporterHeaders = "OTEL_EXPORTER_OTLP_HEADERS"
+	// OtelExporterTimeout      = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	OtelExporterTimeout = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	// OtelLogBatchSize         = "OTEL_BLRP_MAX_EXPORT_BATCH_SIZE"
+	OtelLogBatchSize = "OTEL_BLRP_MAX_EXPORT_BATCH_SIZE"
+	// OtelLogScheduleDelay     = "OTEL_BLRP_SCHEDULE_DELAY"
+	OtelLogScheduleDelay = "OTEL_BLRP_SCHEDULE_DELAY"
+	// OtelLogMaxQueueSize      = "OTEL_BLRP_MAX_QUEUE_SIZE"
+	OtelLogMaxQueueSize = "OTEL_BLRP_MAX_QUEUE_SIZE"
 
 	// *** Sentry Environment Variables ***
 	// SentryDsnEnv  = "SENTRY_DSN"
@@ -1,6 +1,6 @@
 module github.com/cultureamp/glamplify
 
-go 1.20
+go 1.21
 
 require (
 	github.com/DataDog/datadog-lambda-go v1.9.0
@@ -0,0 +1,163 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/jwt"
+)
+
+const defaultAccessLogEvent = "http_request"
+
+// AccessLogConfig for setting initial values for AccessLogMiddleware
+type AccessLogConfig struct {
+	// Writer the access log is written to. Default is the same writer as New().
+	Writer Writer
+	// Event name of the access log entry. Default "http_request".
+	Event string
+	// ExcludePaths are not logged, eg. "/health". A path ending in "*" excludes every path that starts with it.
+	ExcludePaths []string
+	// Headers whose values are logged, eg. "Accept-Language". No headers are logged by default.
+	Headers []string
+	// Route returns the route to log, eg. "/surveys/{id}". Default is the request path.
+	Route func(r *http.Request) string
+	// JwtDecoder decodes the customer and user from the Authorization header. Default reads AUTH_PUBLIC_KEY.
+	JwtDecoder jwt.DecodeJwtToken
+}
+
+// AccessLogMiddleware writes one entry per request with the method, route, status, response size, time taken,
+// user agent, remote ip and the request scoped fields. 5xx responses are logged as ERROR, 4xx as WARN and the rest as INFO.
+// If the request doesn't have RequestScopedFields they are added (see gcontext.WrapRequest) before next is called.
+func AccessLogMiddleware(next http.Handler, configure ...func(*AccessLogConfig)) http.Handler {
+	conf := AccessLogConfig{
+		Event: defaultAccessLogEvent,
+		Route: func(r *http.Request) string { return r.URL.Path },
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	// build the decoder once, as reading AUTH_PUBLIC_KEY and parsing the key is too slow for every request
+	decoder := conf.JwtDecoder
+	if decoder == nil {
+		decoder = newJwtDecoder()
+	}
+
+	headers := make([]string, 0, len(conf.Headers))
+	for _, header := range conf.Headers {
+		headers = append(headers, http.CanonicalHeaderKey(header))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isExcludedPath(r.URL.Path, conf.ExcludePaths) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = wrapRequest(r, decoder)
+		rr := newResponseRecorder(w)
+		start := time.Now()
+
+		defer func() {
+			p := recover()
+
+			status := rr.Status()
+			if p != nil {
+				status = http.StatusInternalServerError
+			}
+			fields := Fields{
+				Method:        r.Method,
+				Route:         conf.Route(r),
+				Status:        status,
+				ResponseBytes: rr.bytes,
+				UserAgent:     r.UserAgent(),
+				RemoteIP:      remoteIP(r),
+			}.Merge(NewDurationFields(time.Since(start)))
+			if len(headers) > 0 {
+				fields[Headers] = headerFields(r, headers)
+			}
+
+			logger := FromContext(r.Context())
+			if conf.Writer != nil {
+				logger = NewFromCtxWithCustomerWriter(r.Context(), conf.Writer)
+			}
+
+			switch {
+			case p != nil:
+				logger.Error(conf.Event, fmt.Errorf("panic: %v", p), fields)
+				panic(p)
+			case status >= http.StatusInternalServerError:
+				logger.Error(conf.Event, errors.New(http.StatusText(status)), fields)
+			case status >= http.StatusBadRequest:
+				logger.Warn(conf.Event, fields)
+			default:
+				logger.Info(conf.Event, fields)
+			}
+		}()
+
+		next.ServeHTTP(rr, r)
+	})
+}
+
+// wrapRequest adds the RequestScopedFields to the request, even if the customer and user can't be decoded
+func wrapRequest(r *http.Request, decoder jwt.DecodeJwtToken) *http.Request {
+	wrapped, _ := gcontext.WrapRequestWithDecoder(r, decoder)
+	return wrapped
+}
+
+// newJwtDecoder returns a decoder using AUTH_PUBLIC_KEY, or if that isn't set one that just uses the ids from the headers
+func newJwtDecoder() jwt.DecodeJwtToken {
+	decoder, err := jwt.NewDecoder()
+	if err != nil {
+		return noJwtDecoder{}
+	}
+	return decoder
+}
+
+type noJwtDecoder struct{}
+
+func (noJwtDecoder) Decode(string) (jwt.Payload, error) {
+	return jwt.Payload{}, errors.New("no jwt decoder")
+}
+
+func isExcludedPath(path string, excluded []string) bool {
+	for _, ex := range excluded {
+		if prefix, ok := strings.CutSuffix(ex, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == ex {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the first X-Forwarded-For address if the request came through a proxy, otherwise the RemoteAddr
+func remoteIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func headerFields(r *http.Request, headers []string) Fields {
+	fields := Fields{}
+	for _, header := range headers {
+		if value := r.Header.Get(header); value != "" {
+			fields[strings.ReplaceAll(strings.ToLower(header), "-", "_")] = value
+		}
+	}
+	return fields
+}
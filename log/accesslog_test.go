@@ -0,0 +1,138 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeJwtDecoder struct{}
+
+func (fakeJwtDecoder) Decode(string) (jwt.Payload, error) {
+	return jwt.Payload{Customer: "hooli", EffectiveUser: "gavin"}, nil
+}
+
+func Test_AccessLogMiddleware(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	handler := AccessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsFields, ok := gcontext.GetRequestScopedFieldsFromRequest(r)
+		assert.True(t, ok)
+		assert.Equal(t, "hooli", rsFields.CustomerAggregateID)
+		_, _ = w.Write([]byte("hello"))
+	}), func(conf *AccessLogConfig) {
+		conf.Writer = NewWriter(func(conf *WriterConfig) {
+			conf.Output = memBuffer
+		})
+		conf.JwtDecoder = fakeJwtDecoder{}
+		conf.Headers = []string{"accept-language"}
+		conf.Route = func(r *http.Request) string { return "/surveys/{id}" }
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/surveys/123", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set(gcontext.RequestIDHeader, "req-1")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Accept-Language", "en-AU")
+	req.Header.Set("Cookie", "secret")
+	req.RemoteAddr = "10.0.0.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	output := memBuffer.String()
+	assert.Contains(t, output, "\"event\":\"http_request\"")
+	assert.Contains(t, output, "\"severity\":\"INFO\"")
+	assert.Contains(t, output, "\"method\":\"GET\"")
+	assert.Contains(t, output, "\"route\":\"/surveys/{id}\"")
+	assert.Contains(t, output, "\"status\":200")
+	assert.Contains(t, output, "\"response_bytes\":5")
+	assert.Contains(t, output, "\"time_taken_ms\":")
+	assert.Contains(t, output, "\"user_agent\":\"test-agent\"")
+	assert.Contains(t, output, "\"remote_ip\":\"10.0.0.1\"")
+	assert.Contains(t, output, "\"request_id\":\"req-1\"")
+	assert.Contains(t, output, "\"customer\":\"hooli\"")
+	assert.Contains(t, output, "\"accept_language\":\"en-AU\"")
+	assert.NotContains(t, output, "secret")
+}
+
+func Test_AccessLogMiddleware_Severity(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	handler := AccessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/failed":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/panic":
+			panic(errors.New("handler panicked"))
+		}
+	}), func(conf *AccessLogConfig) {
+		conf.Writer = NewWriter(func(conf *WriterConfig) {
+			conf.Output = memBuffer
+		})
+		conf.JwtDecoder = fakeJwtDecoder{}
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"WARN\"")
+	assert.Contains(t, memBuffer.String(), "\"status\":404")
+
+	memBuffer.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/failed", nil))
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"ERROR\"")
+	assert.Contains(t, memBuffer.String(), "\"status\":502")
+	assert.Contains(t, memBuffer.String(), "Bad Gateway")
+
+	memBuffer.Reset()
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"ERROR\"")
+	assert.Contains(t, memBuffer.String(), "\"status\":500")
+	assert.Contains(t, memBuffer.String(), "handler panicked")
+}
+
+func Test_AccessLogMiddleware_ExcludePaths(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	handler := AccessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), func(conf *AccessLogConfig) {
+		conf.Writer = NewWriter(func(conf *WriterConfig) {
+			conf.Output = memBuffer
+		})
+		conf.JwtDecoder = fakeJwtDecoder{}
+		conf.ExcludePaths = []string{"/health", "/internal/*"}
+	})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/internal/metrics", nil))
+	assert.Empty(t, memBuffer.String())
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthy", nil))
+	assert.Contains(t, memBuffer.String(), "\"route\":\"/healthy\"")
+}
+
+func Test_AccessLog_RemoteIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "10.0.0.1", remoteIP(req))
+
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.2")
+	assert.Equal(t, "203.0.113.9", remoteIP(req))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "pipe"
+	assert.Equal(t, "pipe", remoteIP(req))
+}
+
+func Test_AccessLog_WithoutPublicKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(gcontext.RequestIDHeader, "req-1")
+
+	wrapped := wrapRequest(req, newJwtDecoder())
+	rsFields, ok := gcontext.GetRequestScopedFieldsFromRequest(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, "req-1", rsFields.RequestID)
+}
@@ -0,0 +1,254 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/env"
+)
+
+// DropPolicy controls what an AsyncWriter does when its queue is full
+type DropPolicy int
+
+const (
+	// BlockWhenFull waits until there is room in the queue (default)
+	BlockWhenFull DropPolicy = iota
+	// DropNewestWhenFull discards the entry being written
+	DropNewestWhenFull
+	// DropOldestWhenFull discards the oldest queued entry to make room for the entry being written
+	DropOldestWhenFull
+)
+
+const (
+	droppedEvent       = "log_entries_dropped"
+	defaultQueueLength = 1024
+)
+
+// AsyncWriterConfig for setting initial values for AsyncWriter
+type AsyncWriterConfig struct {
+	WriterConfig
+	QueueSize int
+	WhenFull  DropPolicy
+}
+
+type asyncEntry struct {
+	sev    string
+	output string
+}
+
+// AsyncWriter formats log entries on the calling go routine, but writes them to the underlying io.Writer
+// from a background go routine so slow outputs don't add latency to the request path.
+// Call Flush before a Lambda freezes and Close on shutdown so queued entries are not lost.
+type AsyncWriter struct {
+	writer   *FieldWriter
+	queue    chan asyncEntry
+	whenFull DropPolicy
+
+	pending int64
+	dropped uint64
+
+	// flushed is closed when pending gets to 0, and is only made while a Flush is waiting
+	flushMutex sync.Mutex
+	flushed    chan struct{}
+
+	mutex  sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewAsyncWriter creates a new AsyncWriter. The optional configure func lets you set values on the underlying writer
+// as well as the queue size (LOG_ASYNC_QUEUE_SIZE, default 1024) and what to do when the queue is full.
+func NewAsyncWriter(configure ...func(*AsyncWriterConfig)) *AsyncWriter {
+	conf := AsyncWriterConfig{
+		WriterConfig: newWriterConfig(),
+		QueueSize:    env.GetInt(env.LogAsyncQueueSize, defaultQueueLength),
+		WhenFull:     BlockWhenFull,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = defaultQueueLength
+	}
+
+	writer := &AsyncWriter{
+		writer:   newFieldWriter(conf.WriterConfig),
+		queue:    make(chan asyncEntry, conf.QueueSize),
+		whenFull: conf.WhenFull,
+		done:     make(chan struct{}),
+	}
+	go writer.run()
+
+	return writer
+}
+
+// WriteFields returns a json string for the given severity and system and user Fields and queues it to be written
+func (writer *AsyncWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	scope := newLogScope(system)
+	json, output := writer.writer.formatFields(system, fields...)
+
+	if writer.isEnabledFor(scope, sev) {
+		writer.enqueue(asyncEntry{sev: sev, output: output})
+	}
+	return json
+}
+
+// ForceWriteFields queues the entry regardless of the level and returns it as a json string
+func (writer *AsyncWriter) ForceWriteFields(sev string, system Fields, fields ...Fields) string {
+	json, output := writer.writer.formatFields(system, fields...)
+	writer.enqueue(asyncEntry{sev: sev, output: output})
+	return json
+}
+
+// IsEnabled returns true if the sev is enabled, false otherwise
+func (writer *AsyncWriter) IsEnabled(sev string) bool {
+	return writer.writer.IsEnabled(sev)
+}
+
+// Levels returns the LevelSwitch so the level and override rules can be changed at runtime
+func (writer *AsyncWriter) Levels() *LevelSwitch {
+	return writer.writer.Levels()
+}
+
+// DebugList returns the customers and users for which every severity is written
+func (writer *AsyncWriter) DebugList() *DebugList {
+	return writer.writer.DebugList()
+}
+
+// FatalHandler returns the FatalHandler configured for this writer, or nil for the default
+func (writer *AsyncWriter) FatalHandler() FatalHandler {
+	return writer.writer.FatalHandler()
+}
+
+func (writer *AsyncWriter) isEnabledFor(scope logScope, sev string) bool {
+	return writer.writer.isEnabledFor(scope, sev)
+}
+
+// Redactor returns the Redactor used to mask sensitive values before they reach this writer
+func (writer *AsyncWriter) Redactor() *Redactor {
+	return writer.writer.Redactor()
+}
+
+// Dropped returns the number of entries dropped since the last "log_entries_dropped" entry was written
+func (writer *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+// Flush waits until all queued entries have been written, or the ctx is done
+func (writer *AsyncWriter) Flush(ctx context.Context) error {
+	writer.flushMutex.Lock()
+	if atomic.LoadInt64(&writer.pending) == 0 {
+		writer.flushMutex.Unlock()
+		return nil
+	}
+	if writer.flushed == nil {
+		writer.flushed = make(chan struct{})
+	}
+	flushed := writer.flushed
+	writer.flushMutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-flushed:
+		return nil
+	}
+}
+
+// Close writes all queued entries and stops the background go routine.
+// Entries written after Close are written synchronously.
+func (writer *AsyncWriter) Close() error {
+	writer.mutex.Lock()
+	if !writer.closed {
+		writer.closed = true
+		close(writer.queue)
+	}
+	writer.mutex.Unlock()
+
+	<-writer.done
+	return nil
+}
+
+func (writer *AsyncWriter) enqueue(entry asyncEntry) {
+	writer.mutex.RLock()
+	defer writer.mutex.RUnlock()
+
+	if writer.closed {
+		writer.writer.write(entry.sev, entry.output)
+		return
+	}
+
+	atomic.AddInt64(&writer.pending, 1)
+	switch writer.whenFull {
+	case DropNewestWhenFull:
+		select {
+		case writer.queue <- entry:
+		default:
+			writer.drop()
+		}
+	case DropOldestWhenFull:
+		for {
+			select {
+			case writer.queue <- entry:
+				return
+			default:
+			}
+
+			// full, so make room by throwing away the oldest entry
+			select {
+			case <-writer.queue:
+				writer.drop()
+			default:
+			}
+		}
+	default:
+		writer.queue <- entry
+	}
+}
+
+func (writer *AsyncWriter) drop() {
+	atomic.AddUint64(&writer.dropped, 1)
+	writer.entryDone()
+}
+
+// entryDone must be called once for each queued entry, after it is written or dropped
+func (writer *AsyncWriter) entryDone() {
+	if atomic.AddInt64(&writer.pending, -1) > 0 {
+		return
+	}
+
+	writer.flushMutex.Lock()
+	defer writer.flushMutex.Unlock()
+
+	// another entry may have been queued, and a Flush started waiting for it, since pending reached 0
+	if atomic.LoadInt64(&writer.pending) == 0 && writer.flushed != nil {
+		close(writer.flushed)
+		writer.flushed = nil
+	}
+}
+
+func (writer *AsyncWriter) run() {
+	defer close(writer.done)
+
+	for entry := range writer.queue {
+		writer.writer.write(entry.sev, entry.output)
+		writer.writeDropped()
+		writer.entryDone()
+	}
+	writer.writeDropped()
+}
+
+func (writer *AsyncWriter) writeDropped() {
+	dropped := atomic.SwapUint64(&writer.dropped, 0)
+	if dropped == 0 {
+		return
+	}
+
+	// the same envelope (product, app, farm etc.) as any other entry, but it isn't part of a request
+	system := newSystemValues().getSystemValues(gcontext.RequestScopedFields{}, Fields{}, droppedEvent, WarnSev, unknownLocation)
+	system[DroppedCount] = dropped
+	_, output := writer.writer.formatFields(system)
+	writer.writer.write(WarnSev, output)
+}
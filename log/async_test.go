@@ -0,0 +1,292 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingBuffer blocks every Write until release is closed
+type blockingBuffer struct {
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlockingBuffer() *blockingBuffer {
+	return &blockingBuffer{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingBuffer) Write(p []byte) (int, error) {
+	b.once.Do(func() { close(b.started) })
+	<-b.release
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *blockingBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func Test_AsyncWriter_WriteFields(t *testing.T) {
+	memBuffer := &blockingBuffer{release: make(chan struct{}), started: make(chan struct{})}
+	close(memBuffer.release)
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	defer writer.Close()
+
+	json := writer.WriteFields(InfoSev, Fields{
+		"system": "system_value",
+	}, Fields{
+		"properties": "properties_value",
+	})
+	assert.Contains(t, json, "\"system\":\"system_value\"")
+
+	err := writer.Flush(context.Background())
+	assert.Nil(t, err)
+
+	msg := memBuffer.String()
+	assert.Contains(t, msg, "\"system\":\"system_value\"")
+	assert.Contains(t, msg, "\"properties\":\"properties_value\"")
+}
+
+func Test_AsyncWriter_Close_WritesQueued(t *testing.T) {
+	memBuffer := newBlockingBuffer()
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.QueueSize = 10
+	})
+
+	for i := 0; i < 5; i++ {
+		writer.WriteFields(InfoSev, Fields{Event: "queued"})
+	}
+	close(memBuffer.release)
+	err := writer.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, strings.Count(memBuffer.String(), "\"event\":\"queued\""))
+
+	// after close we still write, just synchronously
+	writer.WriteFields(InfoSev, Fields{Event: "after_close"})
+	assert.Contains(t, memBuffer.String(), "\"event\":\"after_close\"")
+}
+
+func Test_AsyncWriter_DropNewest(t *testing.T) {
+	memBuffer := newBlockingBuffer()
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.QueueSize = 2
+		conf.WhenFull = DropNewestWhenFull
+	})
+
+	// first entry is picked up by the background writer and blocks
+	writer.WriteFields(InfoSev, Fields{Event: "entry_0"})
+	<-memBuffer.started
+
+	for i := 1; i <= 5; i++ {
+		writer.WriteFields(InfoSev, Fields{Event: "entry_" + string(rune('0'+i))})
+	}
+	assert.Equal(t, uint64(3), writer.Dropped())
+
+	close(memBuffer.release)
+	writer.Close()
+
+	msg := memBuffer.String()
+	assert.Contains(t, msg, "\"event\":\"entry_0\"")
+	assert.Contains(t, msg, "\"event\":\"entry_1\"")
+	assert.Contains(t, msg, "\"event\":\"entry_2\"")
+	assert.NotContains(t, msg, "\"event\":\"entry_5\"")
+	assert.Contains(t, msg, "\"event\":\"log_entries_dropped\"")
+	assert.Contains(t, msg, "\"dropped_count\":3")
+
+	// the dropped entry has the same envelope as any other entry
+	dropped := ""
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.Contains(line, "\"event\":\"log_entries_dropped\"") {
+			dropped = line
+		}
+	}
+	assert.Contains(t, dropped, "\"severity\":\"WARN\"")
+	assert.Contains(t, dropped, "\"product\":\"engagement\"")
+	assert.Contains(t, dropped, "\"app_version\":\"87.23.11\"")
+	assert.Contains(t, dropped, "\"aws_region\":\"us-west-02\"")
+	assert.Contains(t, dropped, "\"resource\":")
+}
+
+func Test_AsyncWriter_DropOldest(t *testing.T) {
+	memBuffer := newBlockingBuffer()
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.QueueSize = 2
+		conf.WhenFull = DropOldestWhenFull
+	})
+
+	writer.WriteFields(InfoSev, Fields{Event: "entry_0"})
+	<-memBuffer.started
+
+	for i := 1; i <= 5; i++ {
+		writer.WriteFields(InfoSev, Fields{Event: "entry_" + string(rune('0'+i))})
+	}
+	assert.Equal(t, uint64(3), writer.Dropped())
+
+	close(memBuffer.release)
+	writer.Close()
+
+	msg := memBuffer.String()
+	assert.Contains(t, msg, "\"event\":\"entry_0\"")
+	assert.NotContains(t, msg, "\"event\":\"entry_1\"")
+	assert.Contains(t, msg, "\"event\":\"entry_4\"")
+	assert.Contains(t, msg, "\"event\":\"entry_5\"")
+	assert.Contains(t, msg, "\"dropped_count\":3")
+}
+
+func Test_AsyncWriter_Flush_Timeout(t *testing.T) {
+	memBuffer := newBlockingBuffer()
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	writer.WriteFields(InfoSev, Fields{Event: "slow"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := writer.Flush(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(memBuffer.release)
+	err = writer.Flush(context.Background())
+	assert.Nil(t, err)
+	writer.Close()
+}
+
+func Test_AsyncWriter_Flush_Waiters(t *testing.T) {
+	memBuffer := newBlockingBuffer()
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	defer writer.Close()
+
+	writer.WriteFields(InfoSev, Fields{Event: "slow"})
+	<-memBuffer.started
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- writer.Flush(context.Background())
+		}()
+	}
+
+	close(memBuffer.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+	assert.Contains(t, memBuffer.String(), "\"event\":\"slow\"")
+	assert.Nil(t, writer.Flush(context.Background()))
+}
+
+func Test_AsyncWriter_Flush_QueuedWhileDone(t *testing.T) {
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	})
+	defer writer.Close()
+
+	// the last entry is done, but another is queued and a Flush waits for it before entryDone gets the lock
+	atomic.StoreInt64(&writer.pending, 1)
+	writer.flushMutex.Lock()
+	done := make(chan struct{})
+	go func() {
+		writer.entryDone()
+		close(done)
+	}()
+	for atomic.LoadInt64(&writer.pending) != 0 {
+		runtime.Gosched()
+	}
+	atomic.AddInt64(&writer.pending, 1)
+	flushed := make(chan struct{})
+	writer.flushed = flushed
+	writer.flushMutex.Unlock()
+	<-done
+
+	select {
+	case <-flushed:
+		assert.Fail(t, "flushed while an entry is still pending")
+	default:
+	}
+
+	writer.entryDone()
+	<-flushed
+}
+
+func Test_AsyncWriter_IsEnabled(t *testing.T) {
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Level = WarnSev
+	})
+	defer writer.Close()
+
+	assert.False(t, writer.IsEnabled(InfoSev))
+	assert.True(t, writer.IsEnabled(WarnSev))
+	assert.True(t, writer.IsEnabled(ErrorSev))
+}
+
+func Test_AsyncWriter_Logger(t *testing.T) {
+	memBuffer := newBlockingBuffer()
+	close(memBuffer.release)
+
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	defer writer.Close()
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Event("async_event").Info("hello")
+	writer.Flush(context.Background())
+
+	msg := memBuffer.String()
+	assert.Contains(t, msg, "\"event\":\"async_event\"")
+	assert.Contains(t, msg, "\"trace_id\":\"1-2-3\"")
+}
+
+func BenchmarkAsyncLogging(b *testing.B) {
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.WhenFull = DropNewestWhenFull
+	})
+	defer writer.Close()
+	logger := newLogger(rsFields, writer)
+
+	fields := Fields{
+		"string": "hello",
+		"int":    123,
+	}
+
+	for n := 0; n < b.N; n++ {
+		logger.Info("test details", fields)
+	}
+}
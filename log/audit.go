@@ -0,0 +1,268 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	systemLog "log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cultureamp/glamplify/env"
+)
+
+const (
+	// AuditSuccess is the outcome of an action that succeeded
+	AuditSuccess = "success"
+	// AuditFailure is the outcome of an action that failed
+	AuditFailure = "failure"
+	// AuditDenied is the outcome of an action that was not allowed
+	AuditDenied = "denied"
+
+	// AuditCheckpointEvent is the event of the signed checkpoints written to the audit log
+	AuditCheckpointEvent = "audit_checkpoint"
+
+	defaultAuditCheckpointEvery = 100
+)
+
+// AuditWriterConfig for setting initial values for AuditWriter
+type AuditWriterConfig struct {
+	// Output the audit log is written to. Default os.Stdout.
+	Output io.Writer
+	// Next writer for all the other severities. Default is the same writer as New().
+	Next Writer
+	// Key used to hash the entries and sign the checkpoints with HMAC-SHA256. Default LOG_AUDIT_KEY. Required.
+	Key []byte
+	// CheckpointEvery is how many entries are written between checkpoints. Default LOG_AUDIT_CHECKPOINT_EVERY or 100.
+	CheckpointEvery int
+}
+
+// AuditWriter writes AUDIT entries to a dedicated tamper evident stream, and everything else to the Next writer.
+// Each entry must have a customer, an actor (the user, or the real_user property), and action, target and outcome properties.
+// Entries are numbered ("seq") and chained with the hash of the previous entry ("prev_hash") and their own HMAC hash ("hash"),
+// so without the key they can't be recomputed. Every CheckpointEvery entries an "audit_checkpoint" is written with an HMAC
+// signature of the seq and hash. Close writes a final checkpoint, so that removing entries from the end can be detected.
+// Use VerifyAuditLog to detect gaps or modifications.
+type AuditWriter struct {
+	nextWriter
+	output          io.Writer
+	key             []byte
+	checkpointEvery uint64
+
+	mutex    sync.Mutex
+	seq      uint64
+	lastHash string
+	rejected uint64
+}
+
+// NewAuditWriter creates a new AuditWriter. It returns an error if there is no Key, as the log wouldn't be tamper evident.
+func NewAuditWriter(configure ...func(*AuditWriterConfig)) (*AuditWriter, error) {
+	conf := AuditWriterConfig{
+		Output:          os.Stdout,
+		Next:            internalWriter,
+		Key:             []byte(env.GetString(env.LogAuditKey, "")),
+		CheckpointEvery: env.GetInt(env.LogAuditCheckpointEvery, defaultAuditCheckpointEvery),
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if len(conf.Key) == 0 {
+		return nil, errors.New("audit writer needs a key, set " + env.LogAuditKey + " or AuditWriterConfig.Key")
+	}
+	if conf.CheckpointEvery <= 0 {
+		conf.CheckpointEvery = defaultAuditCheckpointEvery
+	}
+
+	return &AuditWriter{
+		nextWriter:      nextWriter{next: conf.Next},
+		output:          conf.Output,
+		key:             conf.Key,
+		checkpointEvery: uint64(conf.CheckpointEvery),
+	}, nil
+}
+
+// WriteFields writes AUDIT entries to the audit log and returns them as a json string.
+// Entries that don't match the audit schema are not written and "" is returned.
+// Other severities are written to the Next writer.
+func (writer *AuditWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	if sev != AuditSev {
+		return writer.next.WriteFields(sev, system, fields...)
+	}
+
+	return writer.writeAudit(system, fields...)
+}
+
+// ForceWriteFields writes AUDIT entries to the audit log, and other severities to the Next writer regardless of its level
+func (writer *AuditWriter) ForceWriteFields(sev string, system Fields, fields ...Fields) string {
+	if sev != AuditSev {
+		return writeFields(writer.next, true, sev, system, fields...)
+	}
+
+	return writer.writeAudit(system, fields...)
+}
+
+// IsEnabled returns true for AUDIT, otherwise if the sev is enabled for the Next writer
+func (writer *AuditWriter) IsEnabled(sev string) bool {
+	return sev == AuditSev || writer.next.IsEnabled(sev)
+}
+
+// Checkpoint writes a signed checkpoint now, eg. before shutting down, so that every entry is covered by a signature
+func (writer *AuditWriter) Checkpoint() {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.checkpoint(false)
+}
+
+// Close writes a final checkpoint that ends the chain. Call it before shutting down, so that every entry is covered by a
+// signature and VerifyAuditLog allows the next process to start a new chain. Entries written after Close start a new chain.
+func (writer *AuditWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.checkpoint(true)
+	writer.seq = 0
+	writer.lastHash = ""
+	return nil
+}
+
+// Rejected returns how many AUDIT entries were not written because they didn't match the audit schema
+func (writer *AuditWriter) Rejected() uint64 {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.rejected
+}
+
+func (writer *AuditWriter) isEnabledFor(scope logScope, sev string) bool {
+	if sev == AuditSev {
+		return true
+	}
+	if sw, ok := writer.next.(scopedWriter); ok {
+		return sw.isEnabledFor(scope, sev)
+	}
+	return writer.next.IsEnabled(sev)
+}
+
+func (writer *AuditWriter) writeAudit(system Fields, fields ...Fields) string {
+	entry, err := newAuditEntry(system, fields...)
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if err != nil {
+		writer.rejected++
+		systemLog.Printf("rejected audit entry '%v': %s", system[Event], err.Error())
+		return ""
+	}
+
+	writer.seq++
+	entry[Seq] = writer.seq
+	entry[PrevHash] = writer.lastHash
+
+	// the hash is of the entry without the hash, so it is added to the end of the json
+	json := entry.ToJSON(false)
+	writer.lastHash = auditHash(writer.key, json)
+	json = appendAuditHash(json, writer.lastHash)
+	writer.writeLine(json)
+
+	if writer.seq%writer.checkpointEvery == 0 {
+		writer.checkpoint(false)
+	}
+	return json
+}
+
+func (writer *AuditWriter) checkpoint(final bool) {
+	if writer.seq == 0 {
+		return
+	}
+
+	checkpoint := Fields{
+		Time:      time.Now().UTC().Format(RFC3339Milli),
+		Event:     AuditCheckpointEvent,
+		Severity:  AuditSev,
+		Seq:       writer.seq,
+		Hash:      writer.lastHash,
+		Signature: auditSignature(writer.key, writer.seq, writer.lastHash, final),
+	}
+	if final {
+		checkpoint[Final] = true
+	}
+	writer.writeLine(checkpoint.ToJSON(false))
+}
+
+func (writer *AuditWriter) writeLine(json string) {
+	if _, err := io.WriteString(writer.output, json+"\n"); err != nil {
+		systemLog.Printf("failed to write audit entry: %s", err.Error())
+	}
+}
+
+// newAuditEntry checks the entry has the audit schema and moves the audit fields to the top level
+func newAuditEntry(system Fields, fields ...Fields) (Fields, error) {
+	entry := system.ToSnakeCase()
+	properties := Fields{}.Merge(fields...).ToSnakeCase()
+
+	customer, _ := entry[Customer].(string)
+	if customer == "" {
+		return nil, errors.New("missing customer")
+	}
+
+	actor := Fields{}
+	if user, _ := entry[User].(string); user != "" {
+		actor[EffectiveUser] = user
+	}
+	if realUser, _ := properties[RealUser].(string); realUser != "" {
+		actor[RealUser] = realUser
+	}
+	delete(properties, RealUser)
+	if len(actor) == 0 {
+		return nil, errors.New("missing actor")
+	}
+	entry[Actor] = actor
+
+	for _, key := range []string{Action, Target, Outcome} {
+		value, _ := properties[key].(string)
+		if value == "" {
+			return nil, errors.New("missing " + key)
+		}
+		entry[key] = value
+		delete(properties, key)
+	}
+
+	switch entry[Outcome] {
+	case AuditSuccess, AuditFailure, AuditDenied:
+	default:
+		return nil, errors.New("outcome must be one of success, failure or denied")
+	}
+
+	if len(properties) > 0 {
+		entry[Properties] = properties
+	}
+	return entry, nil
+}
+
+func auditHash(key []byte, json string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(json))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func appendAuditHash(json string, hash string) string {
+	return strings.TrimSuffix(json, "}") + ",\"" + Hash + "\":\"" + hash + "\"}"
+}
+
+func auditSignature(key []byte, seq uint64, hash string, final bool) string {
+	parts := []string{AuditCheckpointEvent, strconv.FormatUint(seq, 10), hash}
+	if final {
+		parts = append(parts, Final)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(strings.Join(parts, ":")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,200 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var auditKey = []byte("audit-key")
+
+func auditFields(action string) Fields {
+	return Fields{
+		Action:   action,
+		Target:   "survey/123",
+		Outcome:  AuditSuccess,
+		RealUser: "richard",
+		"reason": "cleanup",
+	}
+}
+
+func Test_AuditWriter_Entry(t *testing.T) {
+	auditBuffer, nextBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = auditBuffer
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Output = nextBuffer
+		})
+		conf.Key = auditKey
+	})
+	assert.Nil(t, err)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	result := logger.Audit("survey_deleted", auditFields("delete"))
+	logger.Info("survey_loaded")
+
+	assert.NotEmpty(t, result)
+	assert.Equal(t, result+"\n", auditBuffer.String())
+	assert.Contains(t, nextBuffer.String(), "\"event\":\"survey_loaded\"")
+	assert.NotContains(t, nextBuffer.String(), "survey_deleted")
+
+	entry := Fields{}
+	assert.Nil(t, json.Unmarshal([]byte(result), &entry))
+	assert.Equal(t, "survey_deleted", entry[Event])
+	assert.Equal(t, AuditSev, entry[Severity])
+	assert.Equal(t, "hooli", entry[Customer])
+	assert.Equal(t, map[string]interface{}{"effective_user": "UserAggregateID-123", "real_user": "richard"}, entry[Actor])
+	assert.Equal(t, "delete", entry[Action])
+	assert.Equal(t, "survey/123", entry[Target])
+	assert.Equal(t, AuditSuccess, entry[Outcome])
+	assert.Equal(t, map[string]interface{}{"reason": "cleanup"}, entry[Properties])
+	assert.Equal(t, float64(1), entry[Seq])
+	assert.Equal(t, "", entry[PrevHash])
+	assert.Len(t, entry[Hash], 64)
+	assert.True(t, strings.HasSuffix(result, "\"hash\":\""+entry[Hash].(string)+"\"}"))
+}
+
+func Test_AuditWriter_Chain(t *testing.T) {
+	auditBuffer, nextBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = auditBuffer
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Output = nextBuffer
+		})
+		conf.Key = auditKey
+		conf.CheckpointEvery = 2
+	})
+	assert.Nil(t, err)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	for i := 0; i < 5; i++ {
+		logger.Audit("survey_deleted", auditFields("delete"))
+	}
+
+	lines := strings.Split(strings.TrimSpace(auditBuffer.String()), "\n")
+	assert.Len(t, lines, 7)
+
+	var prev Fields
+	for _, line := range lines {
+		entry := Fields{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		if entry[Event] == AuditCheckpointEvent {
+			assert.Equal(t, prev[Seq], entry[Seq])
+			assert.Equal(t, prev[Hash], entry[Hash])
+			assert.NotEmpty(t, entry[Signature])
+			continue
+		}
+		if prev != nil {
+			assert.Equal(t, prev[Hash], entry[PrevHash])
+		}
+		prev = entry
+	}
+
+	writer.Checkpoint()
+	assert.Contains(t, auditBuffer.String(), "\"seq\":5,\"severity\":\"AUDIT\",\"signature\"")
+}
+
+func Test_AuditWriter_Schema(t *testing.T) {
+	auditBuffer, nextBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = auditBuffer
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Output = nextBuffer
+		})
+		conf.Key = auditKey
+	})
+	assert.Nil(t, err)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	assert.Empty(t, logger.Audit("survey_deleted"))
+	assert.Empty(t, logger.Audit("survey_deleted", Fields{Action: "delete", Target: "survey/123"}))
+	assert.Empty(t, logger.Audit("survey_deleted", Fields{Action: "delete", Target: "survey/123", Outcome: "maybe"}))
+
+	logger = NewWitCustomWriter(rsFields, writer)
+	logger.rsFields.CustomerAggregateID = ""
+	assert.Empty(t, logger.Audit("survey_deleted", auditFields("delete")))
+
+	logger = NewWitCustomWriter(rsFields, writer)
+	logger.rsFields.UserAggregateID = ""
+	assert.NotEmpty(t, logger.Audit("survey_deleted", auditFields("delete")))
+
+	assert.Equal(t, uint64(4), writer.Rejected())
+	assert.Equal(t, 1, strings.Count(auditBuffer.String(), "\n"))
+}
+
+func Test_AuditWriter_NoKey(t *testing.T) {
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Key = nil
+	})
+	assert.Nil(t, writer)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "LOG_AUDIT_KEY")
+	}
+}
+
+func Test_AuditWriter_KeyedHash(t *testing.T) {
+	auditBuffer, nextBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = auditBuffer
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Output = nextBuffer
+		})
+		conf.Key = auditKey
+	})
+	assert.Nil(t, err)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	result := logger.Audit("survey_deleted", auditFields("delete"))
+	entry := Fields{}
+	assert.Nil(t, json.Unmarshal([]byte(result), &entry))
+
+	// the hash can't be recomputed without the key
+	unhashed := strings.TrimSuffix(result, ",\"hash\":\""+entry[Hash].(string)+"\"}") + "}"
+	assert.Equal(t, auditHash(auditKey, unhashed), entry[Hash])
+	assert.NotEqual(t, auditHash([]byte("other-key"), unhashed), entry[Hash])
+}
+
+func Test_AuditWriter_Close(t *testing.T) {
+	auditBuffer, nextBuffer := &bytes.Buffer{}, &bytes.Buffer{}
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = auditBuffer
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Output = nextBuffer
+		})
+		conf.Key = auditKey
+	})
+	assert.Nil(t, err)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Audit("survey_deleted", auditFields("delete"))
+	assert.Nil(t, writer.Close())
+	result := logger.Audit("survey_deleted", auditFields("delete"))
+
+	lines := strings.Split(strings.TrimSpace(auditBuffer.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[1], AuditCheckpointEvent)
+	assert.Contains(t, lines[1], "\"final\":true")
+
+	// a new chain starts after Close
+	assert.Contains(t, result, "\"prev_hash\":\"\"")
+	assert.Contains(t, result, "\"seq\":1")
+}
+
+func Test_AuditWriter_Levels(t *testing.T) {
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Level = ErrorSev
+		})
+		conf.Key = auditKey
+	})
+	assert.Nil(t, err)
+
+	assert.True(t, writer.IsEnabled(AuditSev))
+	assert.True(t, writer.IsEnabled(ErrorSev))
+	assert.False(t, writer.IsEnabled(InfoSev))
+	assert.True(t, writer.isEnabledFor(logScope{}, AuditSev))
+}
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const maxAuditLineSize = 1024 * 1024
+
+// AuditProblem is a gap or modification found by VerifyAuditLog
+type AuditProblem struct {
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+// AuditReport is the result of VerifyAuditLog
+type AuditReport struct {
+	// Entries is how many audit entries were read
+	Entries int
+	// Checkpoints is how many checkpoints were read
+	Checkpoints int
+	// Unsigned is how many entries were after the last checkpoint, so removing them can't be detected
+	Unsigned int
+	Problems []AuditProblem
+}
+
+// AuditVerifyConfig for setting options of VerifyAuditLog
+type AuditVerifyConfig struct {
+	// AllowUnsignedTail doesn't report entries after the last checkpoint, eg. when checking the log of a running process. Default false.
+	AllowUnsignedTail bool
+}
+
+// Valid returns true if no gaps or modifications were found
+func (report AuditReport) Valid() bool {
+	return len(report.Problems) == 0
+}
+
+// VerifyAuditLog reads an audit log written by AuditWriter with the same key and reports entries that are missing,
+// out of order or modified, and checkpoints that don't match the entries or have an invalid signature.
+// A new chain (seq 1 without a prev_hash) is only allowed at the start or after a final checkpoint (see AuditWriter.Close),
+// and entries after the last checkpoint are reported unless AllowUnsignedTail is set, as removing them can't otherwise be detected.
+func VerifyAuditLog(r io.Reader, key []byte, configure ...func(*AuditVerifyConfig)) (AuditReport, error) {
+	conf := AuditVerifyConfig{}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	report := AuditReport{}
+	if len(key) == 0 {
+		return report, errors.New("audit log can't be verified without the key")
+	}
+
+	var lastSeq uint64
+	var lastHash string
+	var lastLine int
+	// the start of the stream, or after a final checkpoint, is where a new chain can start
+	closed := true
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		lastLine = line
+
+		problem := func(seq uint64, format string, args ...interface{}) {
+			report.Problems = append(report.Problems, AuditProblem{Line: line, Seq: seq, Reason: fmt.Sprintf(format, args...)})
+		}
+
+		entry, err := decodeAuditLine(text)
+		if err != nil {
+			problem(0, "not a json entry: %s", err.Error())
+			continue
+		}
+
+		seq, err := strconv.ParseUint(entry.string(Seq), 10, 64)
+		if err != nil {
+			problem(0, "missing or invalid %s", Seq)
+			continue
+		}
+		hash := entry.string(Hash)
+
+		if entry.string(Event) == AuditCheckpointEvent {
+			report.Checkpoints++
+			report.Unsigned = 0
+
+			if seq != lastSeq || hash != lastHash {
+				problem(seq, "checkpoint does not match the entries before it")
+			}
+			final, _ := entry[Final].(bool)
+			if !hmac.Equal([]byte(entry.string(Signature)), []byte(auditSignature(key, seq, hash, final))) {
+				problem(seq, "invalid checkpoint signature")
+			}
+			if final {
+				closed = true
+				lastSeq = 0
+				lastHash = ""
+			}
+			continue
+		}
+
+		report.Entries++
+		report.Unsigned++
+
+		prevHash := entry.string(PrevHash)
+		newChain := seq == 1 && prevHash == ""
+		if newChain && !closed {
+			problem(seq, "a new chain started before the previous chain was closed, entries may be missing")
+		}
+		closed = false
+		if !newChain {
+			if seq != lastSeq+1 {
+				problem(seq, "expected seq %d, entries are missing or out of order", lastSeq+1)
+			}
+			if prevHash != lastHash {
+				problem(seq, "%s does not match the previous entry", PrevHash)
+			}
+		}
+
+		suffix := ",\"" + Hash + "\":\"" + hash + "\"}"
+		if hash == "" || !strings.HasSuffix(text, suffix) || !hmac.Equal([]byte(auditHash(key, strings.TrimSuffix(text, suffix)+"}")), []byte(hash)) {
+			problem(seq, "%s does not match, the entry has been modified", Hash)
+		}
+
+		lastSeq = seq
+		lastHash = hash
+	}
+
+	if report.Unsigned > 0 && !conf.AllowUnsignedTail {
+		report.Problems = append(report.Problems, AuditProblem{
+			Line:   lastLine,
+			Seq:    lastSeq,
+			Reason: fmt.Sprintf("%d entries after the last checkpoint are not signed, entries may be missing from the end", report.Unsigned),
+		})
+	}
+
+	return report, scanner.Err()
+}
+
+type auditLine map[string]interface{}
+
+func decodeAuditLine(text string) (auditLine, error) {
+	entry := auditLine{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+	decoder.UseNumber()
+	err := decoder.Decode(&entry)
+	return entry, err
+}
+
+func (entry auditLine) string(key string) string {
+	switch v := entry[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestAuditLog writes the entries, and closes the writer if closeWriter is true
+func newTestAuditLog(t *testing.T, entries int, closeWriter bool) []string {
+	auditBuffer := &bytes.Buffer{}
+	writer, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = auditBuffer
+		conf.Next = NewWriter(func(conf *WriterConfig) {
+			conf.Output = &bytes.Buffer{}
+		})
+		conf.Key = auditKey
+		conf.CheckpointEvery = 3
+	})
+	assert.Nil(t, err)
+	logger := NewWitCustomWriter(rsFields, writer)
+	for i := 0; i < entries; i++ {
+		assert.NotEmpty(t, logger.Audit("survey_deleted", auditFields("delete")))
+	}
+	if closeWriter {
+		assert.Nil(t, writer.Close())
+	}
+	return strings.Split(strings.TrimSpace(auditBuffer.String()), "\n")
+}
+
+func verifyLines(t *testing.T, lines []string, key []byte, configure ...func(*AuditVerifyConfig)) AuditReport {
+	report, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")+"\n"), key, configure...)
+	assert.Nil(t, err)
+	return report
+}
+
+func Test_VerifyAuditLog_Valid(t *testing.T) {
+	lines := newTestAuditLog(t, 7, true)
+
+	report := verifyLines(t, lines, auditKey)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 7, report.Entries)
+	assert.Equal(t, 3, report.Checkpoints)
+	assert.Equal(t, 0, report.Unsigned)
+
+	// a restarted writer starts a new chain after the final checkpoint
+	report = verifyLines(t, append(lines, newTestAuditLog(t, 2, true)...), auditKey)
+	assert.True(t, report.Valid(), report.Problems)
+	assert.Equal(t, 9, report.Entries)
+}
+
+func Test_VerifyAuditLog_NoKey(t *testing.T) {
+	_, err := VerifyAuditLog(strings.NewReader(""), nil)
+	assert.NotNil(t, err)
+}
+
+func Test_VerifyAuditLog_UnsignedTail(t *testing.T) {
+	lines := newTestAuditLog(t, 7, false)
+
+	report := verifyLines(t, lines, auditKey)
+	assert.False(t, report.Valid())
+	assert.Equal(t, 1, report.Unsigned)
+	if assert.Len(t, report.Problems, 1) {
+		assert.Equal(t, len(lines), report.Problems[0].Line)
+		assert.Contains(t, report.Problems[0].Reason, "not signed")
+	}
+
+	// eg. the log of a process that is still running
+	report = verifyLines(t, lines, auditKey, func(conf *AuditVerifyConfig) {
+		conf.AllowUnsignedTail = true
+	})
+	assert.True(t, report.Valid(), report.Problems)
+
+	// the last checkpoint covers the entries, whether or not it is final, and removing it leaves them unsigned
+	lines = newTestAuditLog(t, 3, true)
+	report = verifyLines(t, lines[:len(lines)-1], auditKey)
+	assert.True(t, report.Valid(), report.Problems)
+	report = verifyLines(t, lines[:len(lines)-2], auditKey)
+	assert.False(t, report.Valid())
+}
+
+func Test_VerifyAuditLog_Restart(t *testing.T) {
+	// the previous chain wasn't closed, eg. its tail was removed and a new chain appended
+	lines := append(newTestAuditLog(t, 4, false), newTestAuditLog(t, 2, true)...)
+
+	report := verifyLines(t, lines, auditKey, func(conf *AuditVerifyConfig) {
+		conf.AllowUnsignedTail = true
+	})
+	assert.False(t, report.Valid())
+	if assert.Len(t, report.Problems, 1) {
+		assert.Equal(t, 6, report.Problems[0].Line)
+		assert.Contains(t, report.Problems[0].Reason, "new chain")
+	}
+}
+
+func Test_VerifyAuditLog_Modified(t *testing.T) {
+	lines := newTestAuditLog(t, 4, true)
+	lines[1] = strings.Replace(lines[1], "\"outcome\":\"success\"", "\"outcome\":\"failure\"", 1)
+
+	report := verifyLines(t, lines, auditKey)
+	assert.False(t, report.Valid())
+	assert.Len(t, report.Problems, 1)
+	assert.Equal(t, 2, report.Problems[0].Line)
+	assert.Equal(t, uint64(2), report.Problems[0].Seq)
+	assert.Contains(t, report.Problems[0].Reason, "modified")
+}
+
+func Test_VerifyAuditLog_Gap(t *testing.T) {
+	lines := newTestAuditLog(t, 4, true)
+	// remove the 2nd entry
+	lines = append(lines[:1], lines[2:]...)
+
+	report := verifyLines(t, lines, auditKey)
+	assert.False(t, report.Valid())
+	assert.Contains(t, report.Problems[0].Reason, "expected seq 2")
+	assert.Contains(t, report.Problems[1].Reason, "prev_hash")
+}
+
+func Test_VerifyAuditLog_Checkpoint(t *testing.T) {
+	lines := newTestAuditLog(t, 3, false)
+	assert.Contains(t, lines[3], AuditCheckpointEvent)
+
+	// with the wrong key every hash and signature is wrong
+	report := verifyLines(t, lines, []byte("wrong-key"))
+	assert.Len(t, report.Problems, 4)
+	assert.Contains(t, report.Problems[0].Reason, "modified")
+	assert.Contains(t, report.Problems[3].Reason, "signature")
+
+	// removing the last entry is detected by the checkpoint
+	report = verifyLines(t, append(lines[:2], lines[3]), auditKey)
+	assert.Len(t, report.Problems, 1)
+	assert.Contains(t, report.Problems[0].Reason, "checkpoint does not match")
+}
+
+func Test_VerifyAuditLog_NotJSON(t *testing.T) {
+	report := verifyLines(t, []string{"not json", "{\"event\":\"something\"}"}, auditKey)
+	assert.Len(t, report.Problems, 2)
+	assert.Contains(t, report.Problems[0].Reason, "not a json entry")
+	assert.Contains(t, report.Problems[1].Reason, "seq")
+}
@@ -66,13 +66,63 @@ const (
 	TotalItemsProcessed = "total_items_processed"
 	// TotalItemsRequested = "total_items_requested"
 	TotalItemsRequested = "total_items_requested"
+	// DroppedCount     = "dropped_count"
+	DroppedCount = "dropped_count"
+	// SampleRate       = "sample_rate"
+	SampleRate = "sample_rate"
+	// Method           = "method"
+	Method = "method"
+	// Route            = "route"
+	Route = "route"
+	// Status           = "status"
+	Status = "status"
+	// ResponseBytes    = "response_bytes"
+	ResponseBytes = "response_bytes"
+	// UserAgent        = "user_agent"
+	UserAgent = "user_agent"
+	// RemoteIP         = "remote_ip"
+	RemoteIP = "remote_ip"
+	// Headers          = "headers"
+	Headers = "headers"
+	// Actor            = "actor"
+	Actor = "actor"
+	// RealUser         = "real_user"
+	RealUser = "real_user"
+	// EffectiveUser    = "effective_user"
+	EffectiveUser = "effective_user"
+	// Action           = "action"
+	Action = "action"
+	// Target           = "target"
+	Target = "target"
+	// Outcome          = "outcome"
+	Outcome = "outcome"
+	// Seq              = "seq"
+	Seq = "seq"
+	// PrevHash         = "prev_hash"
+	PrevHash = "prev_hash"
+	// Hash             = "hash"
+	Hash = "hash"
+	// Signature        = "signature"
+	Signature = "signature"
+	// Final            = "final"
+	Final = "final"
+	// SuppressedCount  = "suppressed_count"
+	SuppressedCount = "suppressed_count"
+	// FirstSeen        = "first_seen"
+	FirstSeen = "first_seen"
+	// LastSeen         = "last_seen"
+	LastSeen = "last_seen"
 
 	// Severity Values
 
+	// TraceSev  = "TRACE"
+	TraceSev = "TRACE"
 	// DebugSev = "DEBUG"
 	DebugSev = "DEBUG"
 	// InfoSev  = "INFO"
 	InfoSev = "INFO"
+	// NoticeSev = "NOTICE"
+	NoticeSev = "NOTICE"
 	// WarnSev  = "WARN"
 	WarnSev = "WARN"
 	// ErrorSev = "ERROR"
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"context"
+)
+
+// logCtxKey is the type of the keys this package adds to a context
+type logCtxKey int
+
+const (
+	requestBufferCtx logCtxKey = iota
+	loggerCtx
+)
+
+// WithLogger returns a copy of the context containing the logger, so inner layers can log with the same fields
+func WithLogger(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerCtx, logger)
+}
+
+// FromContext returns the logger stored in the context with WithLogger or WithFields.
+// If there isn't one it returns a new logger from the context's RequestScopedFields (see NewFromCtx).
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(loggerCtx).(*Logger); ok && logger != nil {
+		return logger
+	}
+
+	return NewFromCtx(ctx)
+}
+
+// WithFields returns a copy of the context containing a logger with the fields added to those already in the context.
+// eg. ctx = log.WithFields(ctx, log.Fields{"survey_id": id}) then every log.FromContext(ctx) below includes the survey_id
+func WithFields(ctx context.Context, fields ...Fields) context.Context {
+	return WithLogger(ctx, FromContext(ctx).WithFields(fields...))
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FromContext_Fallback(t *testing.T) {
+	ctx := gcontext.AddRequestFields(context.Background(), rsFields)
+
+	logger := FromContext(ctx)
+	assert.Equal(t, rsFields, logger.rsFields)
+
+	logger = FromContext(context.Background())
+	assert.NotNil(t, logger)
+}
+
+func Test_WithLogger(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+
+	ctx := WithLogger(context.Background(), logger)
+	assert.Same(t, logger, FromContext(ctx))
+
+	FromContext(ctx).Info("from_context")
+	assert.Contains(t, memBuffer.String(), "\"event\":\"from_context\"")
+	assert.Contains(t, memBuffer.String(), "\"customer\":\"hooli\"")
+}
+
+func Test_WithFields_Accumulate(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), Fields{"app_layer": "handler"})
+
+	ctx := WithLogger(context.Background(), logger)
+	ctx = WithFields(ctx, Fields{"job_id": "job-1"})
+	inner := WithFields(ctx, Fields{"survey_id": "survey-1", "job_id": "job-2"})
+
+	FromContext(inner).Info("inner_event")
+	output := memBuffer.String()
+	assert.Contains(t, output, "\"app_layer\":\"handler\"")
+	assert.Contains(t, output, "\"survey_id\":\"survey-1\"")
+	assert.Contains(t, output, "\"job_id\":\"job-2\"")
+
+	// outer layers are unchanged
+	memBuffer.Reset()
+	FromContext(ctx).Info("outer_event")
+	assert.Contains(t, memBuffer.String(), "\"job_id\":\"job-1\"")
+	assert.NotContains(t, memBuffer.String(), "survey_id")
+	assert.Len(t, logger.fields, 1)
+}
+
+func Test_WithFields_NoLogger(t *testing.T) {
+	ctx := gcontext.AddRequestFields(context.Background(), rsFields)
+	ctx = WithFields(ctx, Fields{"job_id": "job-1"})
+
+	logger := FromContext(ctx)
+	assert.Equal(t, rsFields, logger.rsFields)
+	assert.Equal(t, "job-1", logger.fields["job_id"])
+}
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	systemLog "log"
+	"strings"
+	"sync"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/env"
+)
+
+const (
+	customerTargetPrefix = "customer:"
+	userTargetPrefix     = "user:"
+)
+
+// DebugTarget turns on all severities for a customer and/or user until it Expires.
+// If both CustomerAggregateID and UserAggregateID are set, then both must match.
+// A zero Expires never expires.
+type DebugTarget struct {
+	CustomerAggregateID string    `json:"customer,omitempty"`
+	UserAggregateID     string    `json:"user,omitempty"`
+	Expires             time.Time `json:"expires,omitempty"`
+}
+
+// DebugTargetSource returns the latest list of DebugTargets (eg. from a parameter store or feature flag)
+type DebugTargetSource func() ([]DebugTarget, error)
+
+// DebugList holds the customers and users for which loggers write every severity, regardless of the current level.
+// Useful for turning on DEBUG for a single customer while investigating a problem.
+type DebugList struct {
+	mutex   sync.RWMutex
+	targets []DebugTarget
+	now     func() time.Time
+}
+
+// NewDebugList creates a new DebugList with the given targets
+func NewDebugList(targets ...DebugTarget) *DebugList {
+	list := &DebugList{now: time.Now}
+	list.Set(targets...)
+	return list
+}
+
+// NewDebugListFromEnv creates a new DebugList with the targets in LOG_DEBUG_TARGETS (see ParseDebugTargets for the format)
+func NewDebugListFromEnv() *DebugList {
+	targets, err := ParseDebugTargets(env.GetString(env.LogDebugTargets, ""))
+	if err != nil {
+		systemLog.Printf("ignoring invalid %s: %s", env.LogDebugTargets, err.Error())
+	}
+
+	return NewDebugList(targets...)
+}
+
+// ParseDebugTargets parses a comma separated list of targets in the format "customer:<id>", "user:<id>" or
+// "customer:<id>+user:<id>", each with an optional "@<RFC3339 expiry>"
+// eg. "customer:abc123@2026-01-31T00:00:00Z,user:xyz789"
+func ParseDebugTargets(targets string) ([]DebugTarget, error) {
+	var parsed []DebugTarget
+
+	for _, item := range splitList(targets) {
+		target := DebugTarget{}
+
+		selectors, expires, hasExpiry := strings.Cut(item, "@")
+		if hasExpiry {
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(expires))
+			if err != nil {
+				return nil, fmt.Errorf("debug target '%s' has an invalid expiry: %w", item, err)
+			}
+			target.Expires = t
+		}
+
+		for _, selector := range strings.Split(selectors, "+") {
+			selector = strings.TrimSpace(selector)
+			switch {
+			case strings.HasPrefix(selector, customerTargetPrefix):
+				target.CustomerAggregateID = strings.TrimPrefix(selector, customerTargetPrefix)
+			case strings.HasPrefix(selector, userTargetPrefix):
+				target.UserAggregateID = strings.TrimPrefix(selector, userTargetPrefix)
+			default:
+				return nil, fmt.Errorf("debug target '%s' must start with '%s' or '%s'", item, customerTargetPrefix, userTargetPrefix)
+			}
+		}
+
+		parsed = append(parsed, target)
+	}
+
+	return parsed, nil
+}
+
+// Set replaces all the targets. Targets without a customer or user are ignored.
+func (list *DebugList) Set(targets ...DebugTarget) {
+	valid := make([]DebugTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.CustomerAggregateID != "" || target.UserAggregateID != "" {
+			valid = append(valid, target)
+		}
+	}
+
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+
+	list.targets = valid
+}
+
+// Add adds a target
+func (list *DebugList) Add(target DebugTarget) {
+	list.Set(append(list.Targets(), target)...)
+}
+
+// Targets returns the targets that have not yet expired
+func (list *DebugList) Targets() []DebugTarget {
+	list.mutex.RLock()
+	defer list.mutex.RUnlock()
+
+	now := list.now()
+	active := make([]DebugTarget, 0, len(list.targets))
+	for _, target := range list.targets {
+		if !target.expired(now) {
+			active = append(active, target)
+		}
+	}
+
+	return active
+}
+
+// Matches returns true if the RequestScopedFields match any target that has not yet expired
+func (list *DebugList) Matches(rsFields gcontext.RequestScopedFields) bool {
+	return list.matches(rsFields.CustomerAggregateID, rsFields.UserAggregateID)
+}
+
+// Refresh replaces the targets with those returned by the source. On error the current targets are kept.
+func (list *DebugList) Refresh(source DebugTargetSource) error {
+	targets, err := source()
+	if err != nil {
+		return err
+	}
+
+	list.Set(targets...)
+	return nil
+}
+
+// RefreshEvery calls Refresh on a background go routine every interval until the ctx is done
+func (list *DebugList) RefreshEvery(ctx context.Context, interval time.Duration, source DebugTargetSource) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := list.Refresh(source); err != nil {
+					systemLog.Printf("failed to refresh log debug targets: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (list *DebugList) matches(customer string, user string) bool {
+	if list == nil || (customer == "" && user == "") {
+		return false
+	}
+
+	list.mutex.RLock()
+	defer list.mutex.RUnlock()
+
+	if len(list.targets) == 0 {
+		return false
+	}
+
+	now := list.now()
+	for _, target := range list.targets {
+		if target.matches(customer, user) && !target.expired(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (target DebugTarget) matches(customer string, user string) bool {
+	if target.CustomerAggregateID != "" && target.CustomerAggregateID != customer {
+		return false
+	}
+	if target.UserAggregateID != "" && target.UserAggregateID != user {
+		return false
+	}
+
+	return true
+}
+
+func (target DebugTarget) expired(now time.Time) bool {
+	return !target.Expires.IsZero() && now.After(target.Expires)
+}
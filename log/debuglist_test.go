@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/env"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DebugList_Matches(t *testing.T) {
+	list := NewDebugList(
+		DebugTarget{CustomerAggregateID: "hooli"},
+		DebugTarget{CustomerAggregateID: "piedpiper", UserAggregateID: "richard"},
+		DebugTarget{},
+	)
+
+	assert.Len(t, list.Targets(), 2)
+	assert.True(t, list.Matches(gcontext.RequestScopedFields{CustomerAggregateID: "hooli", UserAggregateID: "gavin"}))
+	assert.True(t, list.Matches(gcontext.RequestScopedFields{CustomerAggregateID: "piedpiper", UserAggregateID: "richard"}))
+	assert.False(t, list.Matches(gcontext.RequestScopedFields{CustomerAggregateID: "piedpiper", UserAggregateID: "jared"}))
+	assert.False(t, list.Matches(gcontext.RequestScopedFields{}))
+
+	var nilList *DebugList
+	assert.False(t, nilList.Matches(gcontext.RequestScopedFields{CustomerAggregateID: "hooli"}))
+}
+
+func Test_DebugList_Expiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	list := NewDebugList(DebugTarget{UserAggregateID: "gavin", Expires: now.Add(time.Hour)})
+	list.now = func() time.Time { return now }
+
+	assert.True(t, list.Matches(gcontext.RequestScopedFields{UserAggregateID: "gavin"}))
+
+	now = now.Add(2 * time.Hour)
+	assert.False(t, list.Matches(gcontext.RequestScopedFields{UserAggregateID: "gavin"}))
+	assert.Empty(t, list.Targets())
+}
+
+func Test_DebugList_Add_Refresh(t *testing.T) {
+	list := NewDebugList()
+	list.Add(DebugTarget{CustomerAggregateID: "hooli"})
+	assert.Len(t, list.Targets(), 1)
+
+	err := list.Refresh(func() ([]DebugTarget, error) {
+		return []DebugTarget{{CustomerAggregateID: "a"}, {CustomerAggregateID: "b"}}, nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, list.Targets(), 2)
+
+	err = list.Refresh(func() ([]DebugTarget, error) {
+		return nil, errors.New("unavailable")
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, list.Targets(), 2)
+}
+
+func Test_DebugList_RefreshEvery(t *testing.T) {
+	list := NewDebugList()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list.RefreshEvery(ctx, time.Millisecond, func() ([]DebugTarget, error) {
+		return []DebugTarget{{CustomerAggregateID: "hooli"}}, nil
+	})
+	assert.Eventually(t, func() bool { return len(list.Targets()) == 1 }, time.Second, time.Millisecond)
+}
+
+func Test_ParseDebugTargets(t *testing.T) {
+	targets, err := ParseDebugTargets("customer:hooli@2026-01-31T00:00:00Z, user:gavin, customer:piedpiper+user:richard")
+	assert.Nil(t, err)
+	assert.Equal(t, []DebugTarget{
+		{CustomerAggregateID: "hooli", Expires: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{UserAggregateID: "gavin"},
+		{CustomerAggregateID: "piedpiper", UserAggregateID: "richard"},
+	}, targets)
+
+	_, err = ParseDebugTargets("account:hooli")
+	assert.NotNil(t, err)
+	_, err = ParseDebugTargets("customer:hooli@tomorrow")
+	assert.NotNil(t, err)
+}
+
+func Test_DebugList_Env(t *testing.T) {
+	os.Setenv(env.LogDebugTargets, "customer:hooli")
+	defer os.Unsetenv(env.LogDebugTargets)
+
+	list := NewDebugListFromEnv()
+	assert.True(t, list.Matches(gcontext.RequestScopedFields{CustomerAggregateID: "hooli"}))
+}
+
+func Test_DebugList_Logger(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	list := NewDebugList()
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = ErrorSev
+		conf.DebugList = list
+	})
+	debugged := NewWitCustomWriter(gcontext.RequestScopedFields{CustomerAggregateID: "hooli"}, writer)
+	other := NewWitCustomWriter(gcontext.RequestScopedFields{CustomerAggregateID: "other"}, writer)
+
+	debugged.Debug("before")
+	assert.Empty(t, memBuffer.String())
+	assert.False(t, debugged.IsEnabled(DebugSev))
+
+	list.Add(DebugTarget{CustomerAggregateID: "hooli", Expires: time.Now().Add(time.Minute)})
+	debugged.Debug("debugged_event")
+	other.Debug("other_event")
+	assert.Contains(t, memBuffer.String(), "\"event\":\"debugged_event\"")
+	assert.NotContains(t, memBuffer.String(), "\"event\":\"other_event\"")
+	assert.True(t, debugged.IsEnabled(DebugSev))
+	assert.False(t, other.IsEnabled(DebugSev))
+	assert.Same(t, list, writer.DebugList())
+
+	ctx := gcontext.AddRequestFields(context.Background(), gcontext.RequestScopedFields{CustomerAggregateID: "hooli"})
+	slogger := NewSlogLogger(other)
+	slogger.DebugContext(ctx, "slog_event")
+	assert.Contains(t, memBuffer.String(), "\"event\":\"slog_event\"")
+	assert.False(t, slogger.Enabled(context.Background(), slog.LevelDebug))
+}
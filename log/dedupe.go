@@ -0,0 +1,230 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cultureamp/glamplify/env"
+)
+
+const (
+	defaultDedupeWindowInSec     = 60
+	defaultDedupeMaxFingerprints = 10000
+	maxDedupeSweepInterval       = time.Second
+)
+
+// DedupeWriterConfig for setting initial values for DedupeWriter
+type DedupeWriterConfig struct {
+	// Window repeats are suppressed for after the first entry. Default LOG_DEDUPE_WINDOW_IN_SEC or 60 seconds.
+	Window time.Duration
+	// Severities that are deduplicated. Default WARN and ERROR.
+	Severities []string
+	// MaxFingerprints is how many different entries are tracked at once. Entries over the limit are written. Default 10000.
+	MaxFingerprints int
+}
+
+// dedupeKey is the fingerprint of an entry
+type dedupeKey struct {
+	event    string
+	severity string
+	err      string
+	loc      string
+}
+
+type dedupeEntry struct {
+	sev        string
+	system     Fields
+	properties Fields
+	firstSeen  time.Time
+	lastSeen   time.Time
+	suppressed uint64
+}
+
+// DedupeWriter sits in front of another Writer and suppresses repeated entries, eg. every request logging the same
+// error while a dependency is down. Entries with the same event, severity, error message and location are fingerprinted:
+// the first is written and repeats within the Window are not. When the window closes, if any were suppressed, the first
+// entry is written again with "suppressed_count", "first_seen" and "last_seen". Call Close on shutdown to write them early.
+type DedupeWriter struct {
+	nextWriter
+	window          time.Duration
+	severities      map[string]bool
+	maxFingerprints int
+
+	mutex   sync.Mutex
+	entries map[dedupeKey]*dedupeEntry
+	closed  bool
+	now     func() time.Time
+
+	done chan struct{}
+	wait sync.WaitGroup
+}
+
+// NewDedupeWriter creates a new DedupeWriter in front of next, and starts a go routine that writes the summaries
+func NewDedupeWriter(next Writer, configure ...func(*DedupeWriterConfig)) *DedupeWriter {
+	conf := DedupeWriterConfig{
+		Window:          time.Second * time.Duration(env.GetInt(env.LogDedupeWindowInSec, defaultDedupeWindowInSec)),
+		Severities:      []string{WarnSev, ErrorSev},
+		MaxFingerprints: defaultDedupeMaxFingerprints,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if conf.Window <= 0 {
+		conf.Window = time.Second * defaultDedupeWindowInSec
+	}
+	if conf.MaxFingerprints <= 0 {
+		conf.MaxFingerprints = defaultDedupeMaxFingerprints
+	}
+
+	writer := &DedupeWriter{
+		nextWriter:      nextWriter{next: next},
+		window:          conf.Window,
+		severities:      map[string]bool{},
+		maxFingerprints: conf.MaxFingerprints,
+		entries:         map[dedupeKey]*dedupeEntry{},
+		now:             time.Now,
+		done:            make(chan struct{}),
+	}
+	for _, sev := range conf.Severities {
+		writer.severities[sev] = true
+	}
+
+	writer.wait.Add(1)
+	go writer.run()
+
+	return writer
+}
+
+// WriteFields writes the entry to the next writer if it isn't a repeat, and returns "" if it is
+func (writer *DedupeWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	if !writer.severities[sev] {
+		return writer.next.WriteFields(sev, system, fields...)
+	}
+
+	key := newDedupeKey(sev, system)
+	now := writer.now()
+
+	writer.mutex.Lock()
+	entry, found := writer.entries[key]
+	if found && now.Sub(entry.firstSeen) < writer.window {
+		entry.suppressed++
+		entry.lastSeen = now
+		writer.mutex.Unlock()
+		return ""
+	}
+
+	// the window has closed but the sweeper hasn't got to it yet
+	delete(writer.entries, key)
+	if !writer.closed && len(writer.entries) < writer.maxFingerprints {
+		writer.entries[key] = &dedupeEntry{
+			sev: sev,
+			// copy as the caller or the next writer may change the fields after logging
+			system:     system.clone(),
+			properties: Fields{}.Merge(fields...).clone(),
+			firstSeen:  now,
+			lastSeen:   now,
+		}
+	}
+	writer.mutex.Unlock()
+
+	if found {
+		writer.writeSummary(entry)
+	}
+	return writer.next.WriteFields(sev, system, fields...)
+}
+
+// Close stops the background go routine and writes the summaries of all the entries that have suppressed repeats.
+// Entries written after Close are not deduplicated.
+func (writer *DedupeWriter) Close() error {
+	writer.mutex.Lock()
+	if writer.closed {
+		writer.mutex.Unlock()
+		return nil
+	}
+	writer.closed = true
+	close(writer.done)
+	writer.mutex.Unlock()
+
+	writer.wait.Wait()
+	writer.sweep(func(*dedupeEntry) bool { return true })
+	return nil
+}
+
+func (writer *DedupeWriter) run() {
+	defer writer.wait.Done()
+
+	interval := writer.window / 2
+	if interval > maxDedupeSweepInterval {
+		interval = maxDedupeSweepInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-writer.done:
+			return
+		case <-ticker.C:
+			writer.sweepExpired()
+		}
+	}
+}
+
+// sweepExpired writes the summaries of the entries whose window has closed
+func (writer *DedupeWriter) sweepExpired() {
+	now := writer.now()
+	writer.sweep(func(entry *dedupeEntry) bool {
+		return now.Sub(entry.firstSeen) >= writer.window
+	})
+}
+
+// sweep forgets the entries that are done, and writes the summaries of those that had repeats in the order they were first seen
+func (writer *DedupeWriter) sweep(done func(*dedupeEntry) bool) {
+	var summaries []*dedupeEntry
+
+	writer.mutex.Lock()
+	for key, entry := range writer.entries {
+		if !done(entry) {
+			continue
+		}
+		delete(writer.entries, key)
+		if entry.suppressed > 0 {
+			summaries = append(summaries, entry)
+		}
+	}
+	writer.mutex.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].firstSeen.Before(summaries[j].firstSeen)
+	})
+	for _, entry := range summaries {
+		writer.writeSummary(entry)
+	}
+}
+
+// writeSummary writes the first entry again with how many repeats were suppressed, and when
+func (writer *DedupeWriter) writeSummary(entry *dedupeEntry) {
+	if entry.suppressed == 0 {
+		return
+	}
+
+	system := entry.system.clone()
+	system[Time] = writer.now().UTC().Format(RFC3339Milli)
+	system[SuppressedCount] = entry.suppressed
+	system[FirstSeen] = entry.firstSeen.UTC().Format(RFC3339Milli)
+	system[LastSeen] = entry.lastSeen.UTC().Format(RFC3339Milli)
+
+	// the first entry was written, so write the summary even if the level has changed since
+	writeFields(writer.next, true, entry.sev, system, entry.properties.clone())
+}
+
+func newDedupeKey(sev string, system Fields) dedupeKey {
+	key := dedupeKey{severity: sev}
+	key.event, _ = system[Event].(string)
+	key.loc, _ = system[Loc].(string)
+	if exception, ok := system[Exception].(Fields); ok {
+		key.err, _ = exception["error"].(string)
+	}
+	return key
+}
@@ -0,0 +1,323 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer lets the test read what the sweeper go routine writes
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func Test_DedupeWriter_SuppressesRepeats(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	defer writer.Close()
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	written := 0
+	for i := 0; i < 10; i++ {
+		if logger.Error("downstream_failed", errors.New("connection refused")) != "" {
+			written++
+		}
+	}
+
+	assert.Equal(t, 1, written)
+	assert.Equal(t, 1, strings.Count(memBuffer.String(), "\"event\":\"downstream_failed\""))
+	assert.NotContains(t, memBuffer.String(), SuppressedCount)
+}
+
+func Test_DedupeWriter_Fingerprint(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	defer writer.Close()
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	for i := 0; i < 2; i++ {
+		assert.NotEmpty(t, logger.Error("downstream_failed", errors.New("connection refused "+string(rune('a'+i)))))
+	}
+	for i := 0; i < 2; i++ {
+		assert.NotEmpty(t, logger.Error("other_event_"+string(rune('a'+i)), errors.New("connection refused")))
+	}
+	assert.NotEmpty(t, logger.Warn("downstream_failed"))
+
+	// same event, severity and error, but from a different line
+	assert.NotEmpty(t, logger.Error("repeated", errors.New("e")))
+	assert.NotEmpty(t, logger.Error("repeated", errors.New("e")))
+}
+
+func Test_DedupeWriter_Severities(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	defer writer.Close()
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	for i := 0; i < 3; i++ {
+		assert.NotEmpty(t, logger.Info("busy_event"))
+		assert.NotEmpty(t, logger.Audit("audit_event"))
+	}
+
+	writer = NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+		conf.Severities = []string{InfoSev}
+	})
+	defer writer.Close()
+	logger = NewWitCustomWriter(rsFields, writer)
+
+	written := 0
+	for i := 0; i < 3; i++ {
+		if logger.Info("busy_event") != "" {
+			written++
+		}
+	}
+	assert.Equal(t, 1, written)
+}
+
+func Test_DedupeWriter_SummaryWhenWindowCloses(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Minute
+	})
+	defer writer.Close()
+	start := time.Date(2020, 9, 14, 1, 2, 3, 0, time.UTC)
+	now := start
+	writer.now = func() time.Time { return now }
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logError := func() string {
+		return logger.Error("downstream_failed", errors.New("connection refused"), Fields{"dependency": "authz"})
+	}
+
+	assert.NotEmpty(t, logError())
+	for i := 0; i < 4; i++ {
+		now = now.Add(10 * time.Second)
+		assert.Empty(t, logError())
+	}
+
+	now = start.Add(time.Minute)
+	writer.sweepExpired()
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	summary := lines[1]
+	assert.Contains(t, summary, "\"event\":\"downstream_failed\"")
+	assert.Contains(t, summary, "\"severity\":\"ERROR\"")
+	assert.Contains(t, summary, "\"error\":\"connection refused\"")
+	assert.Contains(t, summary, "\"dependency\":\"authz\"")
+	assert.Contains(t, summary, "\"suppressed_count\":4")
+	assert.Contains(t, summary, "\"first_seen\":\"2020-09-14T01:02:03.000Z\"")
+	assert.Contains(t, summary, "\"last_seen\":\"2020-09-14T01:02:43.000Z\"")
+	assert.Contains(t, summary, "\"time\":\"2020-09-14T01:03:03.000Z\"")
+
+	// a new window starts with the next entry
+	assert.NotEmpty(t, logError())
+	assert.Empty(t, logError())
+}
+
+func Test_DedupeWriter_SummaryBeforeNextWindow(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Minute
+	})
+	defer writer.Close()
+	now := time.Now()
+	writer.now = func() time.Time { return now }
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logError := func() string {
+		return logger.Error("downstream_failed", errors.New("connection refused"))
+	}
+
+	assert.NotEmpty(t, logError())
+	assert.Empty(t, logError())
+
+	// the sweeper hasn't run yet
+	now = now.Add(2 * time.Minute)
+	assert.NotEmpty(t, logError())
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[1], "\"suppressed_count\":1")
+	assert.NotContains(t, lines[2], SuppressedCount)
+}
+
+func Test_DedupeWriter_NoSummaryWithoutRepeats(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	assert.NotEmpty(t, logger.Error("downstream_failed", errors.New("connection refused")))
+	assert.Nil(t, writer.Close())
+
+	assert.Equal(t, 1, strings.Count(memBuffer.String(), "\n"))
+}
+
+func Test_DedupeWriter_Sweeper(t *testing.T) {
+	memBuffer := &syncBuffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = 20 * time.Millisecond
+	})
+	defer writer.Close()
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("slow_dependency")
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(memBuffer.String(), "\"suppressed_count\":2")
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_DedupeWriter_Close(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	for i := 0; i < 3; i++ {
+		logger.Error("event_a", errors.New("a"))
+	}
+	for i := 0; i < 2; i++ {
+		logger.Warn("event_b")
+	}
+
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, writer.Close())
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[2], "\"event\":\"event_a\"")
+	assert.Contains(t, lines[2], "\"suppressed_count\":2")
+	assert.Contains(t, lines[3], "\"event\":\"event_b\"")
+	assert.Contains(t, lines[3], "\"suppressed_count\":1")
+
+	// not deduplicated after Close
+	assert.NotEmpty(t, logger.Warn("event_b"))
+	assert.NotEmpty(t, logger.Warn("event_b"))
+}
+
+func Test_DedupeWriter_MaxFingerprints(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+		conf.MaxFingerprints = 1
+	})
+	defer writer.Close()
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	assert.NotEmpty(t, logger.Warn("event_a"))
+	assert.NotEmpty(t, logger.Warn("event_b"))
+	assert.NotEmpty(t, logger.Warn("event_b"))
+}
+
+func Test_DedupeWriter_Force(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	defer writer.Close()
+
+	system := Fields{Event: "forced", Loc: "here"}
+	assert.NotEmpty(t, writer.WriteFields(ErrorSev, Fields{}.Merge(system)))
+	assert.Empty(t, writer.WriteFields(ErrorSev, Fields{}.Merge(system)))
+	assert.NotEmpty(t, writer.ForceWriteFields(ErrorSev, Fields{}.Merge(system)))
+}
+
+func Test_DedupeWriter_Delegates(t *testing.T) {
+	redactor := NewRedactor()
+	writer := NewDedupeWriter(NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.Level = WarnSev
+		conf.Redactor = redactor
+	}))
+	defer writer.Close()
+
+	assert.False(t, writer.IsEnabled(InfoSev))
+	assert.True(t, writer.IsEnabled(ErrorSev))
+	assert.Same(t, redactor, writer.Redactor())
+	assert.Nil(t, writer.Flush(context.Background()))
+	assert.True(t, writer.isEnabledFor(logScope{}, WarnSev))
+}
+
+func Test_DedupeWriter_CopiesEntry(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewDedupeWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *DedupeWriterConfig) {
+		conf.Window = time.Hour
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	// a reused segment sets the message on every call
+	segment := logger.Event("downstream_failed").Fields(Fields{"attempt": 1})
+	segment.Warn("first")
+	segment.Fields(Fields{"attempt": 2}).Warn("first")
+
+	system := Fields{Event: "direct", Loc: "here", Exception: Fields{"error": "a"}}
+	properties := Fields{"count": 1}
+	writer.WriteFields(ErrorSev, system, properties)
+	writer.WriteFields(ErrorSev, Fields{Event: "direct", Loc: "here", Exception: Fields{"error": "a"}}, Fields{"count": 2})
+	system[Exception].(Fields)["error"] = "changed"
+	properties["count"] = 3
+
+	assert.Nil(t, writer.Close())
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[2], "\"attempt\":1")
+	assert.Contains(t, lines[2], "\"suppressed_count\":1")
+	assert.Contains(t, lines[3], "\"error\":\"a\"")
+	assert.Contains(t, lines[3], "\"count\":1")
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"context"
+	systemLog "log"
+	"os"
+	"time"
+)
+
+const fatalFlushTimeout = 5 * time.Second
+
+// osExit is swapped out by tests
+var osExit = os.Exit
+
+// FatalHandler is called after Fatal writes its entry, with the writer it was written to and the json.
+// Set one on the WriterConfig or with logger.WithFatalHandler. If the handler returns, so does Fatal.
+type FatalHandler func(writer Writer, json string)
+
+// fatalWriter is implemented by writers that have been configured with a FatalHandler
+type fatalWriter interface {
+	FatalHandler() FatalHandler
+}
+
+// PanicOnFatal panics with the json (default)
+func PanicOnFatal(writer Writer, json string) {
+	panic(json)
+}
+
+// ExitOnFatal flushes the writer, if it queues entries (eg. AsyncWriter), then exits the process with the code
+func ExitOnFatal(code int) FatalHandler {
+	return func(writer Writer, json string) {
+		if f, ok := writer.(flusher); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), fatalFlushTimeout)
+			if err := f.Flush(ctx); err != nil {
+				systemLog.Printf("failed to flush log writer before exiting: %s", err.Error())
+			}
+			cancel()
+		}
+
+		osExit(code)
+	}
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Fatal_Default_Panics(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	defer func() {
+		// the panic value is the json, which is what was written
+		p := recover()
+		assert.NotNil(t, p)
+		assert.Equal(t, memBuffer.String(), p.(string)+"\n")
+	}()
+	logger.Fatal("fatal_event", errors.New("fatal"))
+}
+
+func Test_Fatal_ExitOnFatal(t *testing.T) {
+	exitCode := -1
+	original := osExit
+	osExit = func(code int) { exitCode = code }
+	defer func() { osExit = original }()
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.FatalHandler = ExitOnFatal(3)
+	})
+	defer writer.Close()
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	assert.NotPanics(t, func() {
+		logger.Event("fatal_event").Fatal(errors.New("fatal"))
+	})
+	assert.Equal(t, 3, exitCode)
+	// flushed before exiting
+	assert.Contains(t, memBuffer.String(), "\"event\":\"fatal_event\"")
+}
+
+func Test_Fatal_WithFatalHandler(t *testing.T) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.FatalHandler = ExitOnFatal(1)
+	})
+
+	var handled string
+	logger := NewWitCustomWriter(rsFields, writer).WithFatalHandler(func(writer Writer, json string) {
+		handled = json
+	})
+
+	logger.Fatal("fatal_event", errors.New("fatal"))
+	assert.Contains(t, handled, "\"event\":\"fatal_event\"")
+}
+
+func Test_Fatal_WrappedWriter(t *testing.T) {
+	var handled string
+	inner := NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.FatalHandler = func(writer Writer, json string) {
+			handled = json
+		}
+	})
+	audit, err := NewAuditWriter(func(conf *AuditWriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.Next = inner
+		conf.Key = auditKey
+	})
+	assert.Nil(t, err)
+	dedupe := NewDedupeWriter(inner)
+	defer dedupe.Close()
+
+	writers := map[string]Writer{
+		"multi":         NewMultiWriter(Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = &bytes.Buffer{} })}, Sink{Writer: inner}),
+		"sampling":      NewSamplingWriter(inner),
+		"tracesampling": NewTraceSamplingWriter(inner),
+		"audit":         audit,
+		"validating":    NewValidatingWriter(inner),
+		"dedupe":        dedupe,
+		"nested":        NewSamplingWriter(NewValidatingWriter(inner)),
+	}
+
+	for name, writer := range writers {
+		handled = ""
+		logger := NewWitCustomWriter(rsFields, writer)
+		assert.NotPanics(t, func() {
+			logger.Fatal("fatal_event", errors.New("fatal"))
+		}, name)
+		assert.Contains(t, handled, "\"event\":\"fatal_event\"", name)
+	}
+
+	// without a handler the default still panics
+	assert.Panics(t, func() {
+		NewWitCustomWriter(rsFields, NewSamplingWriter(NewWriter(func(conf *WriterConfig) {
+			conf.Output = &bytes.Buffer{}
+		}))).Fatal("fatal_event", errors.New("fatal"))
+	})
+}
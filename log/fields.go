@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	systemLog "log"
-	"reflect"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/cultureamp/glamplify/helper"
@@ -14,6 +14,13 @@ import (
 // Fields type, used to pass to Debug, Print and Error.
 type Fields map[string]interface{}
 
+const maxCachedKeys = 4096
+
+var (
+	snakeMutex sync.RWMutex
+	snakeCache = map[string]string{}
+)
+
 // NewDurationFields add TimeTaken and TimeTakenMS fields given a time.Duration
 func NewDurationFields(duration time.Duration) Fields {
 	return Fields{
@@ -39,6 +46,25 @@ func (fields Fields) Merge(other ...Fields) Fields {
 	return merged
 }
 
+// clone returns a copy of the fields and any nested Fields, so it can be kept after the caller changes them
+func (fields Fields) clone() Fields {
+	cloned := make(Fields, len(fields))
+	for k, v := range fields {
+		switch f := v.(type) {
+		case Fields:
+			v = f.clone()
+		case []Fields:
+			list := make([]Fields, len(f))
+			for i, item := range f {
+				list[i] = item.clone()
+			}
+			v = list
+		}
+		cloned[k] = v
+	}
+	return cloned
+}
+
 // ToSnakeCase converts all fields to snake case
 func (fields Fields) ToSnakeCase() Fields {
 	snaked := Fields{}
@@ -49,21 +75,39 @@ func (fields Fields) ToSnakeCase() Fields {
 			v = f.ToSnakeCase()
 		}
 
-		sc := helper.ToSnakeCase(k)
+		sc := snakeCase(k)
 		snaked[sc] = v
 	}
 
 	return snaked
 }
 
-// ToJSON converts Fields to JSON
+// ToJSON converts Fields to JSON.
+// Errors are written as their message, Stringers with String(), durations as ISO8601 and values that can't be
+// serialized (eg. funcs and chans) as a marker with their type, including in nested Fields, maps and slices.
 func (fields Fields) ToJSON(omitempty bool) string {
-	filtered := fields.filterNonSerializableValues().omitEmpty(omitempty)
-	bytes, err := json.Marshal(filtered)
+	return fields.serialize().marshal(omitempty)
+}
+
+// Serialize returns the fields with their values converted the way ToJSON writes them, and empty strings removed
+// if omitempty is true. Writers that map entries onto another format use it instead of decoding the json.
+func (fields Fields) Serialize(omitempty bool) Fields {
+	return fields.serialize().omitEmpty(omitempty)
+}
+
+// marshal returns fields that have already been serialized as a json string, so writers that also need the
+// serialized fields (eg. for another format) only serialize them once
+func (fields Fields) marshal(omitempty bool) string {
+	serialized := fields.omitEmpty(omitempty)
+	bytes, err := json.Marshal(serialized)
 	if err != nil {
-		buf := debug.Stack()
-		systemLog.Printf("failed to serialize log fields to json string. err: %s, stacktrack: %s", err.Error(), string(buf))
-		// REVISIT - panic?
+		// eg. a json.Marshaler failed, so write the rest of the entry rather than nothing
+		systemLog.Printf("failed to serialize log fields to json string, writing each field instead. err: %s", err.Error())
+		bytes, err = serialized.marshalEachField()
+		if err != nil {
+			buf := debug.Stack()
+			systemLog.Printf("failed to serialize log fields to json string. err: %s, stacktrack: %s", err.Error(), string(buf))
+		}
 	}
 
 	return string(bytes)
@@ -91,20 +135,25 @@ func (fields Fields) ToTags(omitempty bool) []string {
 	return tags
 }
 
-func (fields Fields) filterNonSerializableValues() Fields {
-	filtered := Fields{}
-	for k, v := range fields {
-		vt := reflect.TypeOf(v).Kind()
-
-		switch vt {
-		case reflect.Func, reflect.Chan : // add other types we don't want to log here
-			continue
-		default:
-			filtered[k] = v
-		}
+// snakeCase returns helper.ToSnakeCase(s), cached as the same events and keys are logged over and over
+func snakeCase(s string) string {
+	snakeMutex.RLock()
+	sc, ok := snakeCache[s]
+	snakeMutex.RUnlock()
+	if ok {
+		return sc
 	}
 
-	return filtered
+	sc = helper.ToSnakeCase(s)
+
+	snakeMutex.Lock()
+	defer snakeMutex.Unlock()
+
+	// don't grow forever if keys are generated at runtime
+	if len(snakeCache) < maxCachedKeys {
+		snakeCache[s] = sc
+	}
+	return sc
 }
 
 func (fields Fields) omitEmpty(omitEmpty bool) Fields {
@@ -132,6 +181,8 @@ func (fields Fields) omitEmpty(omitEmpty bool) Fields {
 
 // ValidateNewRelic checks that Entries are valid according to NewRelic requirements before processing
 // https://docs.newrelic.com/docs/insights/insights-data-sources/custom-data/insights-custom-data-requirements-limits
+//
+// Deprecated: register an EventSchema in a SchemaRegistry and use a ValidatingWriter instead.
 func (fields Fields) ValidateNewRelic() (bool, error) {
 	for k, v := range fields {
 		switch s := v.(type) {
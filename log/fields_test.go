@@ -92,7 +92,7 @@ func TestFields_InvalidValues_ToJSON(t *testing.T) {
 	}
 
 	str := fields.ToJSON(false)
-	assert.Equal(t, "{\"key_string\":\"abc\"}", str)
+	assert.Equal(t, "{\"key_chan\":\"[unserializable chan string]\",\"key_func\":\"[unserializable func() int64]\",\"key_string\":\"abc\"}", str)
 }
 
 func TestFields_ToTags(t *testing.T) {
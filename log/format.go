@@ -0,0 +1,195 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// JSONFormat writes each entry as a single line of json (default)
+	JSONFormat = "json"
+	// ConsoleFormat writes each entry as aligned columns followed by key=value pairs. Great for local development.
+	ConsoleFormat = "console"
+	// LogfmtFormat writes each entry as a single line of key=value pairs
+	LogfmtFormat = "logfmt"
+
+	severityWidth = 6
+	eventWidth    = 32
+	traceIndent   = "    "
+	causedBy      = "caused by: "
+	gcStats       = "gc_stats"
+	exceptionErr  = "error"
+	exceptionType = "type"
+	exceptionStk  = "trace"
+	exceptionFrm  = "frames"
+	exceptionCse  = "causes"
+)
+
+// consoleOmitted are the keys that are the same for every entry when running locally, so are left off the console format
+var consoleOmitted = map[string]bool{
+	Resource:     true,
+	Os:           true,
+	Product:      true,
+	App:          true,
+	Farm:         true,
+	AppVer:       true,
+	AwsRegion:    true,
+	AwsAccountID: true,
+}
+
+// formatEntry returns the entry in the given format. The entry must already be snake_cased.
+func formatEntry(format string, entry Fields, json string) string {
+	switch strings.ToLower(format) {
+	case ConsoleFormat:
+		return formatConsole(entry)
+	case LogfmtFormat:
+		return formatLogfmt(entry)
+	default:
+		return json
+	}
+}
+
+func formatConsole(entry Fields) string {
+	properties, _ := entry[Properties].(Fields)
+
+	var b strings.Builder
+	b.WriteString(formatString(entry[Time]))
+	b.WriteString(" ")
+	b.WriteString(padRight(formatString(entry[Severity]), severityWidth))
+	b.WriteString(" ")
+	b.WriteString(padRight(formatString(entry[Event]), eventWidth))
+	if message := formatString(properties[Message]); message != "" {
+		b.WriteString(" ")
+		b.WriteString(message)
+	}
+
+	// properties first as they are what you are usually looking for
+	pairs := appendPairs(nil, "", properties, func(key string) bool { return key == Message })
+	pairs = appendPairs(pairs, "", entry, func(key string) bool {
+		return key == Time || key == Severity || key == Event || key == Properties || key == Exception || consoleOmitted[key]
+	})
+
+	exception, _ := entry[Exception].(Fields)
+	pairs = appendPairs(pairs, Exception+".", exception, func(key string) bool {
+		return key == exceptionStk || key == exceptionFrm || key == exceptionCse || key == gcStats
+	})
+
+	for _, pair := range pairs {
+		b.WriteString(" ")
+		b.WriteString(pair)
+	}
+
+	if trace := formatString(exception[exceptionStk]); trace != "" {
+		b.WriteString("\n")
+		b.WriteString(indent(trace, traceIndent))
+	}
+	causes, _ := exception[exceptionCse].([]Fields)
+	for _, cause := range causes {
+		b.WriteString("\n")
+		b.WriteString(traceIndent + causedBy + formatString(cause[exceptionType]) + ": " + formatString(cause[exceptionErr]))
+	}
+
+	b.WriteString("\n")
+	return b.String()
+}
+
+func formatLogfmt(entry Fields) string {
+	var pairs []string
+	for _, k := range []string{Time, Severity, Event} {
+		pairs = appendPair(pairs, k, entry[k])
+	}
+
+	pairs = appendPairs(pairs, "", entry, func(key string) bool {
+		return key == Time || key == Severity || key == Event || key == Properties || key == Exception
+	})
+	properties, _ := entry[Properties].(Fields)
+	pairs = appendPairs(pairs, "", properties, nil)
+	exception, _ := entry[Exception].(Fields)
+	pairs = appendPairs(pairs, Exception+".", exception, func(key string) bool {
+		return key == exceptionFrm || key == exceptionCse || key == gcStats
+	})
+
+	return strings.Join(pairs, " ") + "\n"
+}
+
+// appendPairs flattens the fields into key=value pairs, with nested Fields joined by "."
+func appendPairs(pairs []string, prefix string, fields Fields, skip func(string) bool) []string {
+	for _, k := range sortedKeys(fields) {
+		if skip != nil && skip(k) {
+			continue
+		}
+
+		if nested, ok := fields[k].(Fields); ok {
+			pairs = appendPairs(pairs, prefix+k+".", nested, nil)
+			continue
+		}
+		pairs = appendPair(pairs, prefix+k, fields[k])
+	}
+
+	return pairs
+}
+
+func appendPair(pairs []string, key string, value interface{}) []string {
+	s := formatString(value)
+	if s == "" {
+		return pairs
+	}
+
+	return append(pairs, key+"="+quoteIfNeeded(s))
+}
+
+func formatString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", v)
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(bytes)
+	}
+}
+
+func quoteIfNeeded(s string) string {
+	if strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+func indent(s string, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cultureamp/glamplify/env"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Format_Console(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Format = ConsoleFormat
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	json := logger.Info("survey_saved", Fields{Message: "saved the survey", "survey_id": 123, "title": "two words"})
+	output := memBuffer.String()
+
+	assert.Contains(t, json, "\"event\":\"survey_saved\"")
+	assert.Contains(t, output, " INFO   survey_saved                     saved the survey ")
+	assert.Contains(t, output, " survey_id=123")
+	assert.Contains(t, output, " title=\"two words\"")
+	assert.Contains(t, output, " customer=hooli")
+	assert.NotContains(t, output, "product=")
+	assert.NotContains(t, output, "{")
+	assert.True(t, strings.HasSuffix(output, "\n"))
+	assert.Equal(t, 1, strings.Count(output, "\n"))
+}
+
+func Test_Format_Console_Trace(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Format = ConsoleFormat
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Error("save_failed", fmt.Errorf("save: %w", errors.New("disk full")))
+	output := memBuffer.String()
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+
+	assert.Contains(t, lines[0], " ERROR  save_failed ")
+	assert.Contains(t, lines[0], " exception.error=\"save: disk full\"")
+	assert.Contains(t, lines[0], " exception.type=*fmt.wrapError")
+	assert.NotContains(t, lines[0], "exception.trace=")
+	assert.NotContains(t, lines[0], "exception.frames=")
+	assert.Equal(t, traceIndent+"caused by: *errors.errorString: disk full", lines[len(lines)-1])
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines[1:] {
+		assert.True(t, strings.HasPrefix(line, traceIndent))
+	}
+}
+
+func Test_Format_Logfmt(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Format = LogfmtFormat
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Warn("quota_low", Fields{"remaining": 5, "detail": Fields{"plan": "free"}})
+	output := memBuffer.String()
+
+	assert.True(t, strings.HasPrefix(output, "time="))
+	assert.Contains(t, output, " severity=WARN event=quota_low ")
+	assert.Contains(t, output, " product=engagement")
+	assert.Contains(t, output, " remaining=5")
+	assert.Contains(t, output, " detail.plan=free")
+	assert.Equal(t, 1, strings.Count(output, "\n"))
+}
+
+func Test_Format_Default_JSON(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	json := logger.Info("survey_saved")
+	assert.Equal(t, json+"\n", memBuffer.String())
+}
+
+func Test_Format_Env(t *testing.T) {
+	os.Setenv(env.LogFormat, LogfmtFormat)
+	defer os.Unsetenv(env.LogFormat)
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("survey_saved")
+	assert.Contains(t, memBuffer.String(), " event=survey_saved ")
+}
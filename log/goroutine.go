@@ -0,0 +1,146 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// GoroutinePanicEvent is the event logged when a function started with Go or Group.Go panics
+	GoroutinePanicEvent = "goroutine_panic"
+
+	// skip runtime.Callers, getCurrentStack (or getCurrentFrames), newPanicError and the deferred func, so the stack starts at the panic
+	panicSkipFrames = 4
+)
+
+// stackError is implemented by errors that captured the stack where they happened (eg. PanicError),
+// so it is logged instead of the stack where the error is logged
+type stackError interface {
+	stackTrace() string
+	stackFrames() []Fields
+}
+
+// PanicError is returned by Go and Group.Wait when a function panics
+type PanicError struct {
+	// Name given to Go or Group.Go
+	Name string
+	// Value passed to panic
+	Value interface{}
+
+	trace  string
+	frames []Fields
+	caller callerInfo
+}
+
+// Error returns the name and the value passed to panic
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", err.Name, err.Value)
+}
+
+// Unwrap returns the value passed to panic, if it is an error
+func (err *PanicError) Unwrap() error {
+	if cause, ok := err.Value.(error); ok {
+		return cause
+	}
+	return nil
+}
+
+func (err *PanicError) stackTrace() string {
+	return err.trace
+}
+
+func (err *PanicError) stackFrames() []Fields {
+	return err.frames
+}
+
+// newPanicError must be called from the deferred func that recovered the panic
+func newPanicError(name string, value interface{}) *PanicError {
+	df := newSystemValues()
+	err := &PanicError{
+		Name:   name,
+		Value:  value,
+		trace:  df.getCurrentStack(panicSkipFrames),
+		frames: df.getCurrentFrames(panicSkipFrames),
+		caller: callerInfo{loc: unknownLocation},
+	}
+
+	// the panic happened in the first frame that isn't part of the runtime (eg. runtime.gopanic or runtime.sigpanic)
+	for _, frame := range err.frames {
+		function, _ := frame["function"].(string)
+		if function == "" || strings.HasPrefix(function, "runtime.") {
+			continue
+		}
+		err.caller = callerInfo{
+			loc: fmt.Sprintf("%v:%v:%s", frame["file"], frame["line"], function),
+			pkg: packageFromFunction(function),
+		}
+		break
+	}
+	return err
+}
+
+// Go runs fn in a new go routine. If fn panics, instead of crashing the process the panic is recovered and logged
+// as ERROR with the logger from ctx (see FromContext), so the entry has the request's fields.
+// The channel receives fn's error, or a *PanicError if it panicked, and is then closed.
+// eg. errc := log.Go(ctx, "send_emails", func(ctx context.Context) error { ... })
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		errc <- runRecovered(ctx, name, fn)
+	}()
+	return errc
+}
+
+// Group runs functions in go routines like Go, and waits for them all to finish.
+// Like golang.org/x/sync/errgroup, the first function to fail (or panic) cancels the group's ctx, and its error is returned by Wait.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wait   sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// NewGroup returns a new Group and a ctx derived from ctx, which is cancelled when a function fails or Wait returns
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go runs fn in a new go routine with the group's ctx. If fn panics the panic is recovered and logged, and Wait returns a *PanicError.
+func (group *Group) Go(name string, fn func(ctx context.Context) error) {
+	group.wait.Add(1)
+	go func() {
+		defer group.wait.Done()
+
+		if err := runRecovered(group.ctx, name, fn); err != nil {
+			group.once.Do(func() {
+				group.err = err
+				group.cancel()
+			})
+		}
+	}()
+}
+
+// Wait waits for all the functions to finish, and returns the first error (or *PanicError)
+func (group *Group) Wait() error {
+	group.wait.Wait()
+	group.cancel()
+	return group.err
+}
+
+func runRecovered(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := newPanicError(name, r)
+			logger := FromContext(ctx)
+			logger.writeFrom(panicErr.caller, logger.rsFields, GoroutinePanicEvent, panicErr, ErrorSev, Fields{"goroutine": name})
+			err = panicErr
+		}
+	}()
+
+	return fn(ctx)
+}
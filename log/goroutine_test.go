@@ -0,0 +1,152 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGoroutineCtx(memBuffer *bytes.Buffer) context.Context {
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+	return WithLogger(context.Background(), logger)
+}
+
+func panickingFunc(ctx context.Context) error {
+	panic("boom")
+}
+
+func Test_Go_Panic(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	ctx := newTestGoroutineCtx(memBuffer)
+
+	err := <-Go(ctx, "send_emails", panickingFunc)
+
+	var panicErr *PanicError
+	if assert.True(t, errors.As(err, &panicErr)) {
+		assert.Equal(t, "send_emails", panicErr.Name)
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.Equal(t, "send_emails panicked: boom", panicErr.Error())
+		assert.Nil(t, panicErr.Unwrap())
+	}
+
+	entry := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(memBuffer.Bytes(), &entry))
+	assert.Equal(t, GoroutinePanicEvent, entry[Event])
+	assert.Equal(t, ErrorSev, entry[Severity])
+	assert.Equal(t, "send_emails", entry[Properties].(map[string]interface{})["goroutine"])
+	assert.Equal(t, "1-2-3", entry[TraceID])
+	assert.Equal(t, "7-8-9", entry[RequestID])
+	assert.Equal(t, "hooli", entry[Customer])
+	assert.Contains(t, entry[Loc], "log.panickingFunc")
+
+	// the stack is where it panicked, not where it was logged
+	exception := entry[Exception].(map[string]interface{})
+	assert.Equal(t, "send_emails panicked: boom", exception["error"])
+	assert.Contains(t, exception["trace"], "panickingFunc")
+	frames := exception["frames"].([]interface{})
+	assert.True(t, len(frames) > 1)
+	assert.Equal(t, "runtime.gopanic", frames[0].(map[string]interface{})["function"])
+	assert.Contains(t, frames[1].(map[string]interface{})["function"], "panickingFunc")
+}
+
+func Test_Go_PanicWithError(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	ctx := newTestGoroutineCtx(memBuffer)
+	cause := errors.New("connection refused")
+
+	err := <-Go(ctx, "worker", func(ctx context.Context) error {
+		panic(cause)
+	})
+
+	assert.True(t, errors.Is(err, cause))
+	assert.Contains(t, memBuffer.String(), "\"error\":\"connection refused\"")
+}
+
+func Test_Go_RuntimePanic(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	ctx := newTestGoroutineCtx(memBuffer)
+
+	err := <-Go(ctx, "worker", func(ctx context.Context) error {
+		var fields Fields
+		fields["key"] = "value"
+		return nil
+	})
+
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Contains(t, err.Error(), "assignment to entry in nil map")
+	assert.Contains(t, memBuffer.String(), "Test_Go_RuntimePanic")
+}
+
+func Test_Go_NoPanic(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	ctx := newTestGoroutineCtx(memBuffer)
+
+	errc := Go(ctx, "worker", func(ctx context.Context) error {
+		return nil
+	})
+	assert.Nil(t, <-errc)
+	_, open := <-errc
+	assert.False(t, open)
+
+	failed := errors.New("failed")
+	assert.Same(t, failed, <-Go(ctx, "worker", func(ctx context.Context) error {
+		return failed
+	}))
+
+	assert.Empty(t, memBuffer.String())
+}
+
+func Test_Go_LoggerFields(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	ctx := WithFields(newTestGoroutineCtx(memBuffer), Fields{"survey_id": "abc"})
+
+	<-Go(ctx, "worker", panickingFunc)
+
+	assert.Contains(t, memBuffer.String(), "\"survey_id\":\"abc\"")
+	assert.Contains(t, memBuffer.String(), "\"request_id\":\"7-8-9\"")
+}
+
+func Test_Group(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	ctx := newTestGoroutineCtx(memBuffer)
+
+	group, groupCtx := NewGroup(ctx)
+	group.Go("panics", panickingFunc)
+	group.Go("waits", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := group.Wait()
+	var panicErr *PanicError
+	if assert.True(t, errors.As(err, &panicErr)) {
+		assert.Equal(t, "panics", panicErr.Name)
+	}
+	assert.NotNil(t, groupCtx.Err())
+	assert.Equal(t, 1, strings.Count(memBuffer.String(), GoroutinePanicEvent))
+}
+
+func Test_Group_NoErrors(t *testing.T) {
+	group, groupCtx := NewGroup(context.Background())
+
+	count := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		i := i
+		group.Go("worker", func(ctx context.Context) error {
+			count <- i
+			return nil
+		})
+	}
+
+	assert.Nil(t, group.Wait())
+	assert.Len(t, count, 3)
+	assert.NotNil(t, groupCtx.Err())
+}
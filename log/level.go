@@ -1,50 +1,147 @@
 package log
 
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gookit/color"
+)
+
 // Leveller manages the conversion of log levels to and from string to int
 type Leveller struct {
-	stol map[string]int
 }
 
+// Levels are spaced out so more can be added in between with RegisterLevel
 const (
-	// DebugLevel = iota
-	DebugLevel = iota
-	// InfoLevel = iota + 1
-	InfoLevel
-	// WarnLevel = iota + 2
-	WarnLevel
-	// ErrorLevel = iota + 3
-	ErrorLevel
-	// FatalLevel = iota + 4
-	FatalLevel
-	// AuditLevel = iota + 5
-	AuditLevel
+	// TraceLevel = 5
+	TraceLevel = 5
+	// DebugLevel = 10
+	DebugLevel = 10
+	// InfoLevel = 20
+	InfoLevel = 20
+	// NoticeLevel = 25
+	NoticeLevel = 25
+	// WarnLevel = 30
+	WarnLevel = 30
+	// ErrorLevel = 40
+	ErrorLevel = 40
+	// FatalLevel = 50
+	FatalLevel = 50
+	// AuditLevel = 60
+	AuditLevel = 60
+)
+
+type levelInfo struct {
+	level int
+	theme *color.Theme
+}
+
+var (
+	levelName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+	// registerMutex serializes RegisterLevel, the map is replaced rather than changed so reads don't need to lock
+	registerMutex    sync.Mutex
+	registeredLevels = newRegisteredLevels()
 )
 
+func newRegisteredLevels() *atomic.Value {
+	levels := &atomic.Value{}
+	levels.Store(map[string]levelInfo{
+		TraceSev:  {level: TraceLevel, theme: color.Secondary},
+		DebugSev:  {level: DebugLevel, theme: color.Debug},
+		InfoSev:   {level: InfoLevel, theme: color.Info},
+		NoticeSev: {level: NoticeLevel, theme: color.Note},
+		WarnSev:   {level: WarnLevel, theme: color.Warn},
+		ErrorSev:  {level: ErrorLevel, theme: color.Error},
+		FatalSev:  {level: FatalLevel, theme: color.Danger},
+		AuditSev:  {level: AuditLevel, theme: color.Notice},
+	})
+	return levels
+}
+
 // NewLevelMap creates a Leveller map
 func NewLevelMap() *Leveller {
-	table := map[string]int{
-		DebugSev: DebugLevel,
-		InfoSev:  InfoLevel,
-		WarnSev:  WarnLevel,
-		ErrorSev: ErrorLevel,
-		FatalSev: FatalLevel,
-		AuditSev: AuditLevel,
+	return &Leveller{}
+}
+
+// RegisterLevel adds a severity, eg. RegisterLevel("VERBOSE", 7, color.Comment) sits between TRACE and DEBUG.
+// The theme is used when LOG_COLOURS is on, and can be nil. Levels can't be changed or removed once registered,
+// so register them at startup (eg. in an init func) before any writers are created.
+func RegisterLevel(name string, level int, theme *color.Theme) error {
+	if !levelName.MatchString(name) {
+		return fmt.Errorf("log level '%s' must be upper case letters, digits and underscores", name)
+	}
+
+	registerMutex.Lock()
+	defer registerMutex.Unlock()
+
+	current := levelTable()
+	if _, found := current[name]; found {
+		return fmt.Errorf("log level '%s' is already registered", name)
+	}
+
+	table := make(map[string]levelInfo, len(current)+1)
+	for k, v := range current {
+		table[k] = v
 	}
-	return &Leveller{
-		stol: table,
+	table[name] = levelInfo{level: level, theme: theme}
+	registeredLevels.Store(table)
+	return nil
+}
+
+// ParseLevel returns the int value of a severity name (case insensitive), or an error if it isn't registered
+func ParseLevel(name string) (int, error) {
+	info, found := levelTable()[strings.ToUpper(strings.TrimSpace(name))]
+	if !found {
+		return 0, fmt.Errorf("unknown log level '%s', must be one of %s", name, strings.Join(levelNames(), ", "))
+	}
+	return info.level, nil
+}
+
+func levelTable() map[string]levelInfo {
+	return registeredLevels.Load().(map[string]levelInfo)
+}
+
+// levelNames returns the registered severities from lowest to highest
+func levelNames() []string {
+	table := levelTable()
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
 	}
+	sort.Slice(names, func(i, j int) bool {
+		if table[names[i]].level == table[names[j]].level {
+			return names[i] < names[j]
+		}
+		return table[names[i]].level < table[names[j]].level
+	})
+	return names
 }
 
-// StringToLevel given a string severity returns the int value
+func levelTheme(severity string) *color.Theme {
+	return levelTable()[severity].theme
+}
+
+// StringToLevel given a string severity returns the int value.
+// Unknown severities return DebugLevel, use ParseLevel to find out if a severity is valid.
 func (sev Leveller) StringToLevel(severity string) int {
-	level, ok := sev.stol[severity]
+	info, ok := levelTable()[severity]
 	if ok {
-		return level
+		return info.level
 	}
 
 	return DebugLevel
 }
 
+func (sev Leveller) isValid(severity string) bool {
+	_, ok := levelTable()[severity]
+	return ok
+}
+
 // ShouldLogSeverity given the current level and a severity returns true if should be logged, false otherwise
 func (sev Leveller) ShouldLogSeverity(level string, severity string) bool {
 	l := sev.StringToLevel(level)
@@ -3,6 +3,7 @@ package log
 import (
 	"testing"
 
+	"github.com/gookit/color"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,3 +50,60 @@ func Test_StringToLevel(t *testing.T) {
 	level = leveller.StringToLevel("bad")
 	assert.Equal(t, DebugLevel, level)
 }
+
+func Test_Levels_Order(t *testing.T) {
+	assert.Equal(t, []string{TraceSev, DebugSev, InfoSev, NoticeSev, WarnSev, ErrorSev, FatalSev, AuditSev}, levelNames())
+
+	leveller := NewLevelMap()
+	assert.True(t, leveller.ShouldLogSeverity(TraceSev, DebugSev))
+	assert.False(t, leveller.ShouldLogSeverity(DebugSev, TraceSev))
+	assert.True(t, leveller.ShouldLogSeverity(InfoSev, NoticeSev))
+	assert.False(t, leveller.ShouldLogSeverity(NoticeSev, InfoSev))
+	assert.True(t, leveller.ShouldLogSeverity(NoticeSev, WarnSev))
+}
+
+func Test_ParseLevel(t *testing.T) {
+	level, err := ParseLevel(TraceSev)
+	assert.Nil(t, err)
+	assert.Equal(t, TraceLevel, level)
+
+	level, err = ParseLevel(" notice ")
+	assert.Nil(t, err)
+	assert.Equal(t, NoticeLevel, level)
+
+	_, err = ParseLevel("DEBGU")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown log level 'DEBGU'")
+	assert.Contains(t, err.Error(), "TRACE, DEBUG, INFO, NOTICE, WARN, ERROR, FATAL, AUDIT")
+
+	_, err = ParseLevel("")
+	assert.NotNil(t, err)
+}
+
+func Test_RegisterLevel(t *testing.T) {
+	defer registeredLevels.Store(levelTable())
+
+	err := RegisterLevel("VERBOSE", 7, color.Comment)
+	assert.Nil(t, err)
+
+	level, err := ParseLevel("verbose")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, level)
+	assert.Equal(t, color.Comment, levelTheme("VERBOSE"))
+
+	leveller := NewLevelMap()
+	assert.True(t, leveller.ShouldLogSeverity("VERBOSE", DebugSev))
+	assert.False(t, leveller.ShouldLogSeverity(DebugSev, "VERBOSE"))
+	assert.True(t, leveller.ShouldLogSeverity(TraceSev, "VERBOSE"))
+
+	err = RegisterLevel("VERBOSE", 8, nil)
+	assert.NotNil(t, err)
+	err = RegisterLevel(InfoSev, 1, nil)
+	assert.NotNil(t, err)
+	err = RegisterLevel("lower", 1, nil)
+	assert.NotNil(t, err)
+	err = RegisterLevel("", 1, nil)
+	assert.NotNil(t, err)
+	_, err = ParseLevel("LOWER")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,337 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	eventRulePrefix   = "event:"
+	packageRulePrefix = "package:"
+)
+
+// LevelRule overrides the level for events starting with EventPrefix and/or written from Package (or its sub packages).
+// Empty EventPrefix or Package match everything.
+type LevelRule struct {
+	EventPrefix string `json:"event_prefix,omitempty"`
+	Package     string `json:"package,omitempty"`
+	Level       string `json:"level"`
+}
+
+// LevelSwitch holds the current level and any override rules. It is safe to change them at runtime
+// (eg. from an admin http endpoint or a signal) while loggers are writing.
+type LevelSwitch struct {
+	mutex    sync.RWMutex
+	leveller *Leveller
+
+	levelName  string
+	level      int
+	rules      []LevelRule
+	ruleLevels []int
+}
+
+// levelChange is the payload for the LevelSwitch http handler
+type levelChange struct {
+	Level string      `json:"level"`
+	Rules []LevelRule `json:"rules"`
+}
+
+// logScope describes where a log entry comes from, so level decisions can be made per event or package
+type logScope struct {
+	event    string
+	pkg      string
+	customer string
+	user     string
+}
+
+// scopedWriter is implemented by writers that can decide if a severity is enabled for a given scope
+type scopedWriter interface {
+	isEnabledFor(scope logScope, sev string) bool
+}
+
+// NewLevelSwitch creates a new LevelSwitch with the given level and optional override rules.
+// Invalid levels fall back to INFO, invalid rules are ignored.
+func NewLevelSwitch(level string, rules ...LevelRule) *LevelSwitch {
+	levels := &LevelSwitch{
+		leveller: NewLevelMap(),
+	}
+
+	if err := levels.SetLevel(level); err != nil {
+		levels.levelName = InfoSev
+		levels.level = InfoLevel
+	}
+	_ = levels.SetRules(rules...)
+
+	return levels
+}
+
+// ParseLevelRules parses a comma separated list of rules in the format "event:authz_*=DEBUG,package:github.com/cultureamp/myapp/db=WARN"
+func ParseLevelRules(rules string) ([]LevelRule, error) {
+	var parsed []LevelRule
+
+	for _, item := range splitList(rules) {
+		selector, level, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("level rule '%s' must be in the format selector=LEVEL", item)
+		}
+
+		rule := LevelRule{Level: strings.TrimSpace(level)}
+		selector = strings.TrimSpace(selector)
+		switch {
+		case strings.HasPrefix(selector, eventRulePrefix):
+			rule.EventPrefix = strings.TrimSuffix(strings.TrimPrefix(selector, eventRulePrefix), "*")
+		case strings.HasPrefix(selector, packageRulePrefix):
+			rule.Package = strings.TrimSuffix(strings.TrimPrefix(selector, packageRulePrefix), "/...")
+		default:
+			return nil, fmt.Errorf("level rule '%s' must start with '%s' or '%s'", item, eventRulePrefix, packageRulePrefix)
+		}
+		parsed = append(parsed, rule)
+	}
+
+	return parsed, nil
+}
+
+// Level returns the current level
+func (levels *LevelSwitch) Level() string {
+	levels.mutex.RLock()
+	defer levels.mutex.RUnlock()
+
+	return levels.levelName
+}
+
+// SetLevel changes the current level
+func (levels *LevelSwitch) SetLevel(level string) error {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if !levels.leveller.isValid(level) {
+		return fmt.Errorf("unknown log level '%s'", level)
+	}
+
+	levels.mutex.Lock()
+	defer levels.mutex.Unlock()
+
+	levels.levelName = level
+	levels.level = levels.leveller.StringToLevel(level)
+	return nil
+}
+
+// Rules returns a copy of the current override rules
+func (levels *LevelSwitch) Rules() []LevelRule {
+	levels.mutex.RLock()
+	defer levels.mutex.RUnlock()
+
+	return append([]LevelRule{}, levels.rules...)
+}
+
+// SetRules replaces the current override rules. The first matching rule wins.
+func (levels *LevelSwitch) SetRules(rules ...LevelRule) error {
+	validated := make([]LevelRule, 0, len(rules))
+	ruleLevels := make([]int, 0, len(rules))
+	for _, rule := range rules {
+		rule.Level = strings.ToUpper(strings.TrimSpace(rule.Level))
+		if !levels.leveller.isValid(rule.Level) {
+			return fmt.Errorf("unknown log level '%s' for rule %+v", rule.Level, rule)
+		}
+		validated = append(validated, rule)
+		ruleLevels = append(ruleLevels, levels.leveller.StringToLevel(rule.Level))
+	}
+
+	levels.mutex.Lock()
+	defer levels.mutex.Unlock()
+
+	levels.rules = validated
+	levels.ruleLevels = ruleLevels
+	return nil
+}
+
+// IsEnabled returns true if the sev could be enabled for any event or package
+func (levels *LevelSwitch) IsEnabled(sev string) bool {
+	levels.mutex.RLock()
+	defer levels.mutex.RUnlock()
+
+	lowest := levels.level
+	for _, l := range levels.ruleLevels {
+		if l < lowest {
+			lowest = l
+		}
+	}
+
+	return levels.leveller.ShouldLogLevel(lowest, levels.leveller.StringToLevel(sev))
+}
+
+// IsEnabledFor returns true if the sev is enabled for the event written from the package.
+// If the event is empty (not known yet) then any event rule for the package is considered.
+func (levels *LevelSwitch) IsEnabledFor(event string, pkg string, sev string) bool {
+	levels.mutex.RLock()
+	defer levels.mutex.RUnlock()
+
+	level := levels.levelFor(event, pkg)
+	return levels.leveller.ShouldLogLevel(level, levels.leveller.StringToLevel(sev))
+}
+
+// ServeHTTP lets you view (GET) and change (PUT or POST) the level and rules at runtime.
+// eg. curl -X PUT -d '{"level":"INFO","rules":[{"event_prefix":"authz_","level":"DEBUG"}]}' http://localhost:8080/admin/log
+// or  curl -X PUT http://localhost:8080/admin/log?level=DEBUG
+// Make sure you protect this endpoint!
+func (levels *LevelSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if err := levels.change(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levelChange{
+		Level: levels.Level(),
+		Rules: levels.Rules(),
+	})
+}
+
+// ToggleOnSignal switches between the current level and the given level each time one of the signals is received
+// (eg. syscall.SIGUSR1). Call the returned stop func to stop listening.
+func (levels *LevelSwitch) ToggleOnSignal(level string, signals ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	done := make(chan struct{})
+	go levels.toggleOn(ch, done, level)
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+func (levels *LevelSwitch) toggleOn(ch <-chan os.Signal, done <-chan struct{}, level string) {
+	original := levels.Level()
+	for {
+		select {
+		case <-ch:
+			current := levels.Level()
+			if current == strings.ToUpper(level) {
+				_ = levels.SetLevel(original)
+			} else {
+				original = current
+				_ = levels.SetLevel(level)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (levels *LevelSwitch) change(r *http.Request) error {
+	if level := r.URL.Query().Get("level"); level != "" {
+		return levels.SetLevel(level)
+	}
+
+	var change levelChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		return fmt.Errorf("invalid level change: %w", err)
+	}
+
+	if change.Level != "" {
+		if err := levels.SetLevel(change.Level); err != nil {
+			return err
+		}
+	}
+	if change.Rules != nil {
+		return levels.SetRules(change.Rules...)
+	}
+
+	return nil
+}
+
+func (levels *LevelSwitch) levelFor(event string, pkg string) int {
+	lowest, found := 0, false
+	for i, rule := range levels.rules {
+		if !rule.matchesPackage(pkg) {
+			continue
+		}
+
+		if rule.EventPrefix != "" && event == "" {
+			// we don't know the event yet, so any event rule for this package could apply
+			if !found || levels.ruleLevels[i] < lowest {
+				lowest, found = levels.ruleLevels[i], true
+			}
+			continue
+		}
+
+		if strings.HasPrefix(event, rule.EventPrefix) {
+			return minLevel(levels.ruleLevels[i], lowest, found)
+		}
+	}
+
+	return minLevel(levels.level, lowest, found)
+}
+
+func (rule LevelRule) matchesPackage(pkg string) bool {
+	if rule.Package == "" {
+		return true
+	}
+
+	return pkg == rule.Package || strings.HasPrefix(pkg, rule.Package+"/")
+}
+
+func minLevel(level int, lowest int, found bool) int {
+	if found && lowest < level {
+		return lowest
+	}
+	return level
+}
+
+func newLogScope(system Fields) logScope {
+	event, _ := system[Event].(string)
+	loc, _ := system[Loc].(string)
+	customer, _ := system[Customer].(string)
+	user, _ := system[User].(string)
+
+	// loc is "file:line:function"
+	function := loc
+	if i := strings.LastIndex(loc, ":"); i >= 0 {
+		function = loc[i+1:]
+	}
+
+	return logScope{
+		event:    event,
+		pkg:      packageFromFunction(function),
+		customer: customer,
+		user:     user,
+	}
+}
+
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	return packageFromFunction(fn.Name())
+}
+
+// packageFromFunction returns the package path for a fully qualified function name
+// eg. "github.com/cultureamp/glamplify/log.(*Segment).Info" => "github.com/cultureamp/glamplify/log"
+func packageFromFunction(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+
+	return function
+}
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cultureamp/glamplify/env"
+	"github.com/stretchr/testify/assert"
+)
+
+const thisPackage = "github.com/cultureamp/glamplify/log"
+
+func Test_LevelSwitch_SetLevel(t *testing.T) {
+	levels := NewLevelSwitch(InfoSev)
+	assert.Equal(t, InfoSev, levels.Level())
+	assert.False(t, levels.IsEnabled(DebugSev))
+
+	err := levels.SetLevel("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, DebugSev, levels.Level())
+	assert.True(t, levels.IsEnabled(DebugSev))
+
+	err = levels.SetLevel("verbose")
+	assert.NotNil(t, err)
+	assert.Equal(t, DebugSev, levels.Level())
+}
+
+func Test_LevelSwitch_EventRules(t *testing.T) {
+	levels := NewLevelSwitch(InfoSev, LevelRule{EventPrefix: "authz_", Level: DebugSev})
+
+	assert.True(t, levels.IsEnabledFor("authz_check", "any/pkg", DebugSev))
+	assert.False(t, levels.IsEnabledFor("survey_saved", "any/pkg", DebugSev))
+	assert.True(t, levels.IsEnabledFor("survey_saved", "any/pkg", InfoSev))
+
+	// unknown event, so the authz rule could apply
+	assert.True(t, levels.IsEnabledFor("", "any/pkg", DebugSev))
+	// could be enabled somewhere
+	assert.True(t, levels.IsEnabled(DebugSev))
+}
+
+func Test_LevelSwitch_PackageRules(t *testing.T) {
+	levels := NewLevelSwitch(DebugSev,
+		LevelRule{Package: "github.com/cultureamp/noisy", Level: WarnSev},
+		LevelRule{EventPrefix: "noisy_but_important", Level: DebugSev},
+	)
+
+	assert.False(t, levels.IsEnabledFor("chatter", "github.com/cultureamp/noisy", InfoSev))
+	assert.False(t, levels.IsEnabledFor("chatter", "github.com/cultureamp/noisy/sub", InfoSev))
+	assert.True(t, levels.IsEnabledFor("chatter", "github.com/cultureamp/noisy", WarnSev))
+	assert.True(t, levels.IsEnabledFor("chatter", "github.com/cultureamp/noisy2", DebugSev))
+	assert.True(t, levels.IsEnabledFor("chatter", "github.com/cultureamp/other", DebugSev))
+}
+
+func Test_LevelSwitch_SetRules_Invalid(t *testing.T) {
+	levels := NewLevelSwitch(InfoSev)
+	err := levels.SetRules(LevelRule{EventPrefix: "a", Level: "LOUD"})
+	assert.NotNil(t, err)
+	assert.Empty(t, levels.Rules())
+}
+
+func Test_ParseLevelRules(t *testing.T) {
+	rules, err := ParseLevelRules("event:authz_*=DEBUG, package:github.com/cultureamp/db/...=WARN")
+	assert.Nil(t, err)
+	assert.Equal(t, []LevelRule{
+		{EventPrefix: "authz_", Level: DebugSev},
+		{Package: "github.com/cultureamp/db", Level: WarnSev},
+	}, rules)
+
+	_, err = ParseLevelRules("authz=DEBUG")
+	assert.NotNil(t, err)
+	_, err = ParseLevelRules("event:authz")
+	assert.NotNil(t, err)
+}
+
+func Test_LevelSwitch_ServeHTTP(t *testing.T) {
+	levels := NewLevelSwitch(InfoSev)
+
+	rr := httptest.NewRecorder()
+	levels.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/log", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "\"level\":\"INFO\"")
+
+	rr = httptest.NewRecorder()
+	body := `{"level":"WARN","rules":[{"event_prefix":"authz_","level":"DEBUG"}]}`
+	levels.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/log", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, WarnSev, levels.Level())
+	assert.Equal(t, []LevelRule{{EventPrefix: "authz_", Level: DebugSev}}, levels.Rules())
+
+	rr = httptest.NewRecorder()
+	levels.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/log?level=error", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ErrorSev, levels.Level())
+
+	rr = httptest.NewRecorder()
+	levels.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/admin/log?level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	levels.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/admin/log", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func Test_LevelSwitch_Toggle(t *testing.T) {
+	levels := NewLevelSwitch(InfoSev)
+
+	ch := make(chan os.Signal)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		levels.toggleOn(ch, done, DebugSev)
+		close(finished)
+	}()
+
+	ch <- syscall.SIGHUP
+	assert.Eventually(t, func() bool { return levels.Level() == DebugSev }, time.Second, time.Millisecond)
+	ch <- syscall.SIGHUP
+	assert.Eventually(t, func() bool { return levels.Level() == InfoSev }, time.Second, time.Millisecond)
+
+	close(done)
+	<-finished
+}
+
+func Test_LevelSwitch_Writer(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	levels := NewLevelSwitch(InfoSev)
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Levels = levels
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Debug("authz_check")
+	assert.Empty(t, memBuffer.String())
+	assert.False(t, logger.IsEnabled(DebugSev))
+
+	_ = levels.SetRules(LevelRule{EventPrefix: "authz_", Level: DebugSev})
+	logger.Debug("authz_check")
+	logger.Debug("survey_check")
+	assert.Contains(t, memBuffer.String(), "\"event\":\"authz_check\"")
+	assert.NotContains(t, memBuffer.String(), "\"event\":\"survey_check\"")
+	assert.True(t, logger.IsEnabled(DebugSev))
+	assert.True(t, logger.Event("authz_check").IsEnabled(DebugSev))
+	assert.False(t, logger.Event("survey_check").IsEnabled(DebugSev))
+
+	_ = levels.SetRules(LevelRule{Package: thisPackage, Level: ErrorSev})
+	memBuffer.Reset()
+	logger.Warn("authz_check")
+	assert.Empty(t, memBuffer.String())
+	assert.False(t, logger.IsEnabled(WarnSev))
+	assert.Same(t, levels, writer.Levels())
+}
+
+func Test_LevelSwitch_Env(t *testing.T) {
+	os.Setenv(env.LogLevelRules, "event:authz_=DEBUG")
+	defer os.Unsetenv(env.LogLevelRules)
+
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Level = ErrorSev
+	})
+	assert.Equal(t, ErrorSev, writer.Levels().Level())
+	assert.True(t, writer.isEnabledFor(logScope{event: "authz_check"}, DebugSev))
+	assert.False(t, writer.isEnabledFor(logScope{event: "other"}, WarnSev))
+}
+
+func Test_PackageFromFunction(t *testing.T) {
+	assert.Equal(t, thisPackage, packageFromFunction(thisPackage+".(*Segment).Info"))
+	assert.Equal(t, "main", packageFromFunction("main.main"))
+	assert.Equal(t, thisPackage, callerPackage(0))
+
+	scope := newLogScope(Fields{Event: "e", Loc: "/src/app/main.go:12:github.com/cultureamp/app.handler.func1"})
+	assert.Equal(t, "e", scope.event)
+	assert.Equal(t, "github.com/cultureamp/app", scope.pkg)
+}
@@ -2,10 +2,11 @@ package log
 
 import (
 	"context"
+	systemLog "log"
 	"net/http"
+	"strings"
 
 	gcontext "github.com/cultureamp/glamplify/context"
-	"github.com/cultureamp/glamplify/helper"
 )
 
 // Logger contains context to be able to write log messages
@@ -14,11 +15,22 @@ type Logger struct {
 	fields    Fields
 	sysValues *SystemValues
 	writer    Writer
+	buffer    *RequestBuffer
+	onFatal   FatalHandler
+
+	disabledJSON bool
 }
 
+const (
+	// skip write and the Logger method (eg. Info) to get to the code that is logging
+	callerSkipFrames = 2
+)
+
 var (
-	internalWriter = NewWriter(func(conf *WriterConfig) {})
-	defaultLogger  = NewWitCustomWriter(gcontext.RequestScopedFields{}, internalWriter)
+	defaultRedactor  = NewRedactor()
+	defaultDebugList = NewDebugListFromEnv()
+	internalWriter   = NewWriter(func(conf *WriterConfig) {})
+	defaultLogger    = NewWitCustomWriter(gcontext.RequestScopedFields{}, internalWriter)
 )
 
 // New creates a *Logger with optional fields. Useful for when you want to add a field to all subsequent logging calls eg. request_id, etc.
@@ -33,16 +45,19 @@ func NewWitCustomWriter(rsFields gcontext.RequestScopedFields, writer Writer, fi
 
 // NewFromCtx creates a new logger from a context, which should contain RequestScopedFields.
 // If the context does not contain then, then this method will NOT add them in.
+// If the context contains a RequestBuffer then the logger will use it.
 func NewFromCtx(ctx context.Context, fields ...Fields) *Logger {
-	rsFields, _ := gcontext.GetRequestScopedFields(ctx)
-	return New(rsFields, fields...)
+	return NewFromCtxWithCustomerWriter(ctx, internalWriter, fields...)
 }
 
 // NewFromCtxWithCustomerWriter creates a new logger from a context, which should contain RequestScopedFields.
 // If the context does not contain then, then this method will NOT add them in.
+// If the context contains a RequestBuffer then the logger will use it.
 func NewFromCtxWithCustomerWriter(ctx context.Context, writer Writer, fields ...Fields) *Logger {
 	rsFields, _ := gcontext.GetRequestScopedFields(ctx)
-	return NewWitCustomWriter(rsFields, writer, fields...)
+	logger := NewWitCustomWriter(rsFields, writer, fields...)
+	logger.buffer, _ = GetRequestBuffer(ctx)
+	return logger
 }
 
 // NewFromRequest creates a new logger from a http.Request, which should contain RequestScopedFields.
@@ -134,21 +149,37 @@ func (logger Logger) Error(event string, err error, fields ...Fields) string {
 // Useful to trace catastrophic errors that are not recoverable. These should always be logged.
 // Use snake_case keys and lower case values if possible.
 func Fatal(rsFields gcontext.RequestScopedFields, event string, err error, fields ...Fields) {
-	event = defaultLogger.write(rsFields, event, err, FatalSev, fields...)
-
-	// time to panic!
-	panic(event)
+	json := defaultLogger.write(rsFields, event, err, FatalSev, fields...)
+	defaultLogger.fatalHandler()(defaultLogger.writer, json)
 }
 
-// Fatal writes a error message with optional types to the underlying standard writer and then calls panic!
-// Panic will terminate the current go routine.
+// Fatal writes a error message with optional types to the underlying standard writer and then calls the FatalHandler.
+// By default this panics, which will terminate the current go routine (see WithFatalHandler and WriterConfig.FatalHandler).
 // Useful to trace catastrophic errors that are not recoverable. These should always be logged.
 // Use snake_case keys and lower case values if possible.
 func (logger Logger) Fatal(event string, err error, fields ...Fields) {
-	event = logger.write(logger.rsFields, event, err, FatalSev, fields...)
+	json := logger.write(logger.rsFields, event, err, FatalSev, fields...)
+	logger.fatalHandler()(logger.writer, json)
+}
+
+// WithFields returns a copy of the logger that adds the fields to every entry, as well as the logger's fields
+func (logger Logger) WithFields(fields ...Fields) *Logger {
+	logger.fields = logger.fields.Merge(fields...)
+	return &logger
+}
 
-	// time to panic!
-	panic(event)
+// WithFatalHandler returns a copy of the logger that calls the handler after writing a FATAL entry,
+// instead of the writer's FatalHandler
+func (logger Logger) WithFatalHandler(handler FatalHandler) *Logger {
+	logger.onFatal = handler
+	return &logger
+}
+
+// WithDisabledJSON returns a copy of the logger that builds the entries of disabled severities and returns them as json,
+// rather than "". They still aren't written. Only use it if you need the returned json, as building it is what costs.
+func (logger Logger) WithDisabledJSON() *Logger {
+	logger.disabledJSON = true
+	return &logger
 }
 
 // Audit writes a write message with optional types to the underlying standard writer.
@@ -165,29 +196,162 @@ func (logger Logger) Audit(event string, fields ...Fields) string {
 	return logger.write(logger.rsFields, event, nil, AuditSev, fields...)
 }
 
+// Log writes a message with the given severity, eg. TRACE, NOTICE or a level added with RegisterLevel.
+// Unknown severities are reported and not written. FATAL entries call the FatalHandler, like Fatal.
+// Use snake_case keys and lower case values if possible.
+func Log(rsFields gcontext.RequestScopedFields, severity string, event string, fields ...Fields) string {
+	severity, ok := checkSeverity(severity, event)
+	if !ok {
+		return ""
+	}
+
+	json := defaultLogger.write(rsFields, event, nil, severity, fields...)
+	if severity == FatalSev {
+		defaultLogger.fatalHandler()(defaultLogger.writer, json)
+	}
+	return json
+}
+
+// Log writes a message with the given severity, eg. TRACE, NOTICE or a level added with RegisterLevel.
+// Unknown severities are reported and not written. FATAL entries call the FatalHandler, like Fatal.
+// Use snake_case keys and lower case values if possible.
+func (logger Logger) Log(severity string, event string, fields ...Fields) string {
+	severity, ok := checkSeverity(severity, event)
+	if !ok {
+		return ""
+	}
+
+	json := logger.write(logger.rsFields, event, nil, severity, fields...)
+	if severity == FatalSev {
+		logger.fatalHandler()(logger.writer, json)
+	}
+	return json
+}
 
 // Event method uses expressive syntax format: logger.Event("event_name").Fields(fields...).Info("message")
 func (logger Logger) Event(event string) *Segment {
 	return &Segment{
 		logger: logger,
-		event: event,
+		event:  event,
 		fields: Fields{},
 	}
 }
 
-// IsEnabled returns true if the given severity is enabled
+// IsEnabled returns true if the given severity is enabled for the calling package
 func (logger Logger) IsEnabled(severity string) bool {
+	return logger.isEnabledFor(logger.newLogScope("", callerPackage(1)), severity)
+}
+
+func (logger Logger) newLogScope(event string, pkg string) logScope {
+	return logScope{
+		event:    event,
+		pkg:      pkg,
+		customer: logger.rsFields.CustomerAggregateID,
+		user:     logger.rsFields.UserAggregateID,
+	}
+}
+
+func (logger Logger) isEnabledFor(scope logScope, severity string) bool {
+	if sw, ok := logger.writer.(scopedWriter); ok {
+		return sw.isEnabledFor(scope, severity)
+	}
 	return logger.writer.IsEnabled(severity)
 }
 
+// write checks the severity is enabled before doing any work. Disabled entries aren't built and return "",
+// unless they are kept by a RequestBuffer, the severity is FATAL or the logger was made WithDisabledJSON.
 func (logger Logger) write(rsFields gcontext.RequestScopedFields, event string, err error, severity string, fields ...Fields) string {
-	event = helper.ToSnakeCase(event)
+	return logger.writeFrom(logger.sysValues.getCaller(callerSkipFrames), rsFields, event, err, severity, fields...)
+}
+
+// writeFrom is write with the location already known, eg. where a recovered panic happened
+func (logger Logger) writeFrom(caller callerInfo, rsFields gcontext.RequestScopedFields, event string, err error, severity string, fields ...Fields) string {
+	logged := event
+	event = snakeCase(event)
+
+	scope := logScope{
+		event:    event,
+		pkg:      caller.pkg,
+		customer: rsFields.CustomerAggregateID,
+		user:     rsFields.UserAggregateID,
+	}
+	if !logger.isEnabledFor(scope, severity) {
+		// FATAL entries are always built, so the FatalHandler gets the json
+		skip := !logger.disabledJSON && severity != FatalSev
+		if skip && logger.buffer == nil {
+			return ""
+		}
+
+		system, properties := logger.newEntry(rsFields, event, err, severity, caller.loc, fields...)
+		if logger.buffer != nil {
+			logger.checkEntry(logged, system, properties)
+			logger.buffer.add(logger.writer, severity, system, properties)
+		}
+		if skip {
+			return ""
+		}
+		return entryJSON(system, properties)
+	}
 
-	properties := logger.fields.Merge(fields...)
-	system := logger.sysValues.getSystemValues(rsFields, properties, event, severity)
+	system, properties := logger.newEntry(rsFields, event, err, severity, caller.loc, fields...)
+	logger.checkEntry(logged, system, properties)
+	if logger.buffer != nil && (severity == ErrorSev || severity == FatalSev) {
+		// write what led up to the error first
+		logger.buffer.Flush()
+	}
+
+	return logger.writer.WriteFields(severity, system, properties)
+}
+
+// newEntry returns the system values and redacted properties for a log entry
+func (logger Logger) newEntry(rsFields gcontext.RequestScopedFields, event string, err error, severity string, loc string, fields ...Fields) (Fields, Fields) {
+	properties := logger.properties(fields...)
+	properties = logger.redactor().Redact(properties)
+	system := logger.sysValues.getSystemValues(rsFields, properties, event, severity, loc)
 	if err != nil {
 		system = logger.sysValues.getErrorValues(err, system)
+		logger.redactor().redactErrors(system)
 	}
 
-	return logger.writer.WriteFields(severity, system, properties)
+	return system, properties
+}
+
+// checkEntry passes the entry, with the event name as it was logged, to writers that check it (eg. ValidatingWriter)
+func (logger Logger) checkEntry(event string, system Fields, properties Fields) {
+	if ec, ok := logger.writer.(entryChecker); ok {
+		ec.checkEntry(event, system, properties)
+	}
+}
+
+// properties merges the logger's fields with the given fields into a new map,
+// so writers that keep the entry (eg. DedupeWriter) don't share the caller's map
+func (logger Logger) properties(fields ...Fields) Fields {
+	return logger.fields.Merge(fields...)
+}
+
+// checkSeverity returns the upper case severity, or false if it isn't registered
+func checkSeverity(severity string, event string) (string, bool) {
+	severity = strings.ToUpper(strings.TrimSpace(severity))
+	if _, err := ParseLevel(severity); err != nil {
+		systemLog.Printf("not writing '%s': %s", event, err.Error())
+		return severity, false
+	}
+	return severity, true
+}
+
+func (logger Logger) fatalHandler() FatalHandler {
+	if logger.onFatal != nil {
+		return logger.onFatal
+	}
+	if fw, ok := logger.writer.(fatalWriter); ok && fw.FatalHandler() != nil {
+		return fw.FatalHandler()
+	}
+	return PanicOnFatal
+}
+
+func (logger Logger) redactor() *Redactor {
+	if rw, ok := logger.writer.(RedactingWriter); ok {
+		return rw.Redactor()
+	}
+	return defaultRedactor
 }
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	systemLog "log"
 	"net/http"
+	"os"
 	"testing"
 	"time"
 
@@ -409,6 +411,76 @@ func Test_Log_AuditWithFields(t *testing.T) {
 	assert.Contains(t, json, "\"string3_space\":\"world\"")
 }
 
+func Test_Log_Custom(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = TraceSev
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	json := logger.Log(TraceSev, "trace_event", Fields{"key": "value"})
+	assert.Contains(t, json, "\"event\":\"trace_event\"")
+	assert.Contains(t, json, "\"severity\":\"TRACE\"")
+	assert.Contains(t, json, "\"customer\":\"hooli\"")
+	assert.Contains(t, json, "\"key\":\"value\"")
+
+	json = logger.Log("notice", "notice_event")
+	assert.Contains(t, json, "\"severity\":\"NOTICE\"")
+
+	json = logger.Event("segment_event").Log(NoticeSev, "hello")
+	assert.Contains(t, json, "\"severity\":\"NOTICE\"")
+	assert.Contains(t, json, "\"message\":\"hello\"")
+
+	json = Log(rsFields, WarnSev, "global_event")
+	assert.Contains(t, json, "\"severity\":\"WARN\"")
+	assert.Contains(t, memBuffer.String(), "trace_event")
+}
+
+func Test_Log_Custom_Level(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = NoticeSev
+	}))
+
+	assert.Empty(t, logger.Log(TraceSev, "trace_event"))
+	assert.Empty(t, logger.Info("info_event"))
+	assert.NotEmpty(t, logger.Log(NoticeSev, "notice_event"))
+	assert.NotEmpty(t, logger.Warn("warn_event"))
+	assert.NotContains(t, memBuffer.String(), "trace_event")
+	assert.NotContains(t, memBuffer.String(), "info_event")
+}
+
+func Test_Log_Custom_Unknown(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	systemLog.SetOutput(stderr)
+	defer systemLog.SetOutput(os.Stderr)
+
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+
+	assert.Empty(t, logger.Log("BOGUS", "bogus_event"))
+	assert.Empty(t, memBuffer.String())
+	assert.Contains(t, stderr.String(), "not writing 'bogus_event': unknown log level 'BOGUS'")
+}
+
+func Test_Log_Custom_Fatal(t *testing.T) {
+	var handled string
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.FatalHandler = func(writer Writer, json string) {
+			handled = json
+		}
+	}))
+
+	json := logger.Log(FatalSev, "fatal_event")
+	assert.Contains(t, json, "\"severity\":\"FATAL\"")
+	assert.Equal(t, json, handled)
+}
+
 func Test_Log_Error_WithSubDocument(t *testing.T) {
 
 	t1 := time.Now()
@@ -884,3 +956,98 @@ func BenchmarkLogging(b *testing.B) {
 		logger.Info("test details", fields)
 	}
 }
+
+func BenchmarkLogging_Disabled(b *testing.B) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = io.Discard
+		conf.Level = InfoSev
+	})
+	logger := newLogger(rsFields, writer)
+
+	fields := Fields{
+		"string": "hello",
+		"int":    123,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		logger.Debug("test details", fields)
+	}
+}
+
+func BenchmarkLogging_DisabledJSON(b *testing.B) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = io.Discard
+		conf.Level = InfoSev
+	})
+	logger := newLogger(rsFields, writer).WithDisabledJSON()
+
+	fields := Fields{
+		"string": "hello",
+		"int":    123,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		logger.Debug("test details", fields)
+	}
+}
+
+func BenchmarkLogging_Enabled(b *testing.B) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = io.Discard
+		conf.Level = InfoSev
+	})
+	logger := newLogger(rsFields, writer)
+
+	fields := Fields{
+		"string": "hello",
+		"int":    123,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		logger.Info("test details", fields)
+	}
+}
+
+func Test_Logging_Disabled_Allocations(t *testing.T) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = io.Discard
+		conf.Level = InfoSev
+	})
+	logger := newLogger(rsFields, writer)
+	fields := Fields{"string": "hello"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		logger.Debug("test details", fields)
+	})
+	assert.Zero(t, allocs)
+	assert.Empty(t, logger.Debug("test details", fields))
+
+	// the json is only built if asked for
+	json := logger.WithDisabledJSON().Debug("test details", fields)
+	assert.Contains(t, json, "\"event\":\"test_details\"")
+	assert.Contains(t, json, "\"string\":\"hello\"")
+
+	// FATAL is always built for the FatalHandler
+	var fatalJSON string
+	logger = logger.WithFatalHandler(func(writer Writer, json string) {
+		fatalJSON = json
+	})
+	writer.Levels().SetLevel(AuditSev)
+	logger.Fatal("fatal_event", errors.New("failed"))
+	assert.Contains(t, fatalJSON, "\"event\":\"fatal_event\"")
+}
+
+func Test_Logger_PropertiesCopied(t *testing.T) {
+	logger := NewWitCustomWriter(rsFields, NewWriter())
+
+	fields := Fields{"count": 1}
+	properties := logger.properties(fields)
+	fields["count"] = 2
+	assert.Equal(t, 1, properties["count"])
+}
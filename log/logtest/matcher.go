@@ -0,0 +1,105 @@
+package logtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cultureamp/glamplify/log"
+)
+
+// Matcher checks a recorded entry
+type Matcher interface {
+	Match(entry log.Fields) bool
+	String() string
+}
+
+type matcherFunc struct {
+	description string
+	match       func(entry log.Fields) bool
+}
+
+func (m matcherFunc) Match(entry log.Fields) bool {
+	return m.match(entry)
+}
+
+func (m matcherFunc) String() string {
+	return m.description
+}
+
+// MatcherFunc creates a Matcher from a func. The description is used in failure messages.
+func MatcherFunc(description string, match func(entry log.Fields) bool) Matcher {
+	return matcherFunc{description: description, match: match}
+}
+
+// HasField matches entries where the value at the "." separated path (eg. "exception.error", or "exception.causes.0.error"
+// for an item in an array) equals value.
+// The value is compared after a round trip through json, so HasField("count", 1) matches a decoded 1.0
+func HasField(path string, value interface{}) Matcher {
+	expected := normalise(value)
+	return MatcherFunc(fmt.Sprintf("%s=%v", path, value), func(entry log.Fields) bool {
+		actual, ok := lookup(entry, path)
+		return ok && reflect.DeepEqual(actual, expected)
+	})
+}
+
+// HasKey matches entries that have a value at the "." separated path
+func HasKey(path string) Matcher {
+	return MatcherFunc(fmt.Sprintf("%s present", path), func(entry log.Fields) bool {
+		_, ok := lookup(entry, path)
+		return ok
+	})
+}
+
+// HasProperty matches entries where the property (one of the Fields passed to the logger) equals value
+func HasProperty(key string, value interface{}) Matcher {
+	return HasField(log.Properties+"."+key, value)
+}
+
+// HasMessage matches entries logged with the message
+func HasMessage(message string) Matcher {
+	return HasProperty(log.Message, message)
+}
+
+// HasError matches entries logged with an error with the message
+func HasError(message string) Matcher {
+	return HasField(log.Exception+".error", message)
+}
+
+func lookup(entry log.Fields, path string) (interface{}, bool) {
+	var current interface{} = entry
+	for _, key := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case log.Fields:
+			var ok bool
+			if current, ok = value[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, false
+			}
+			current = value[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func normalise(value interface{}) interface{} {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(bytes, &decoded); err != nil {
+		return value
+	}
+	return toValue(decoded)
+}
@@ -0,0 +1,215 @@
+// Package logtest records log entries in memory so tests can assert on them without parsing json strings.
+package logtest
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cultureamp/glamplify/helper"
+	"github.com/cultureamp/glamplify/log"
+)
+
+// Recorder is a log.Writer that keeps every entry in memory as decoded log.Fields.
+// It records every severity and is safe to share between parallel tests.
+type Recorder struct {
+	mutex     sync.Mutex
+	entries   []log.Fields
+	undecoded []string
+}
+
+// NewRecorder creates a new empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WriteFields records the entry and returns it as a json string, exactly as log.FieldWriter would
+func (recorder *Recorder) WriteFields(sev string, system log.Fields, fields ...log.Fields) string {
+	merged := log.Fields{}
+	properties := merged.Merge(fields...)
+	if len(properties) > 0 {
+		system[log.Properties] = properties
+	}
+
+	jsonStr := system.ToSnakeCase().ToJSON(false)
+	recorder.record(jsonStr)
+	return jsonStr
+}
+
+// record decodes the entry, or keeps the json if it can't be decoded so AssertLogged and AssertNotLogged fail
+func (recorder *Recorder) record(jsonStr string) {
+	var decoded map[string]interface{}
+	err := json.Unmarshal([]byte(jsonStr), &decoded)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if err != nil {
+		recorder.undecoded = append(recorder.undecoded, jsonStr)
+		return
+	}
+	recorder.entries = append(recorder.entries, toFields(decoded))
+}
+
+// IsEnabled always returns true so every entry is recorded
+func (recorder *Recorder) IsEnabled(sev string) bool {
+	return true
+}
+
+// Entries returns a copy of every recorded entry, oldest first
+func (recorder *Recorder) Entries() []log.Fields {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return append([]log.Fields{}, recorder.entries...)
+}
+
+// Undecoded returns the entries that couldn't be decoded from json, oldest first
+func (recorder *Recorder) Undecoded() []string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return append([]string{}, recorder.undecoded...)
+}
+
+// Filter returns the recorded entries for the event (which is snake_cased to match the logger)
+func (recorder *Recorder) Filter(event string) []log.Fields {
+	event = helper.ToSnakeCase(event)
+
+	var filtered []log.Fields
+	for _, entry := range recorder.Entries() {
+		if entry[log.Event] == event {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+// Reset removes all the recorded entries
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.entries = nil
+	recorder.undecoded = nil
+}
+
+// AssertLogged reports an error if no entry has the event and severity and matches all the matchers,
+// or if an entry couldn't be decoded
+func (recorder *Recorder) AssertLogged(t testing.TB, event string, sev string, matchers ...Matcher) bool {
+	t.Helper()
+
+	if !recorder.assertDecoded(t) {
+		return false
+	}
+
+	candidates := recorder.find(event, sev)
+	for _, entry := range candidates {
+		if matchesAll(entry, matchers) {
+			return true
+		}
+	}
+
+	t.Errorf("expected a %s entry for event '%s'%s, but found %d %s entries for that event: %s",
+		sev, helper.ToSnakeCase(event), describe(matchers), len(candidates), sev, toJSON(candidates))
+	return false
+}
+
+// AssertNotLogged reports an error if any entry has the event and severity and matches all the matchers,
+// or if an entry couldn't be decoded
+func (recorder *Recorder) AssertNotLogged(t testing.TB, event string, sev string, matchers ...Matcher) bool {
+	t.Helper()
+
+	if !recorder.assertDecoded(t) {
+		return false
+	}
+
+	for _, entry := range recorder.find(event, sev) {
+		if matchesAll(entry, matchers) {
+			t.Errorf("expected no %s entry for event '%s'%s, but found: %s", sev, helper.ToSnakeCase(event), describe(matchers), toJSON(entry))
+			return false
+		}
+	}
+
+	return true
+}
+
+// assertDecoded reports an error for each entry that couldn't be decoded, as it might be the one being asserted on
+func (recorder *Recorder) assertDecoded(t testing.TB) bool {
+	t.Helper()
+
+	undecoded := recorder.Undecoded()
+	for _, jsonStr := range undecoded {
+		t.Errorf("couldn't decode log entry: '%s'", jsonStr)
+	}
+	return len(undecoded) == 0
+}
+
+func (recorder *Recorder) find(event string, sev string) []log.Fields {
+	var found []log.Fields
+	for _, entry := range recorder.Filter(event) {
+		if entry[log.Severity] == sev {
+			found = append(found, entry)
+		}
+	}
+
+	return found
+}
+
+func matchesAll(entry log.Fields, matchers []Matcher) bool {
+	for _, matcher := range matchers {
+		if !matcher.Match(entry) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func describe(matchers []Matcher) string {
+	if len(matchers) == 0 {
+		return ""
+	}
+
+	descriptions := make([]string, 0, len(matchers))
+	for _, matcher := range matchers {
+		descriptions = append(descriptions, matcher.String())
+	}
+	return " with " + strings.Join(descriptions, " and ")
+}
+
+// toFields converts decoded json objects to log.Fields, including objects in arrays (eg. exception.causes),
+// so nested values can be type asserted the same way
+func toFields(decoded map[string]interface{}) log.Fields {
+	fields := log.Fields{}
+	for k, v := range decoded {
+		fields[k] = toValue(v)
+	}
+
+	return fields
+}
+
+func toValue(decoded interface{}) interface{} {
+	switch v := decoded.(type) {
+	case map[string]interface{}:
+		return toFields(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = toValue(item)
+		}
+		return values
+	}
+
+	return decoded
+}
+
+func toJSON(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(bytes)
+}
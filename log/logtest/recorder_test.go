@@ -0,0 +1,142 @@
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeT captures failures so we can test that assertions fail
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+var rsFields = gcontext.RequestScopedFields{
+	TraceID:             "1-2-3",
+	CustomerAggregateID: "hooli",
+	UserAggregateID:     "gavin",
+}
+
+func Test_Recorder_Entries(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewWitCustomWriter(rsFields, recorder)
+
+	json := logger.Info("surveySaved", log.Fields{"surveyID": 123, "detail": log.Fields{"title": "hello"}})
+	logger.Error("save_failed", errors.New("disk full"))
+
+	entries := recorder.Entries()
+	assert.Len(t, entries, 2)
+	assert.Contains(t, json, "\"event\":\"survey_saved\"")
+	assert.Equal(t, "survey_saved", entries[0][log.Event])
+	assert.Equal(t, log.InfoSev, entries[0][log.Severity])
+	assert.Equal(t, "hooli", entries[0][log.Customer])
+
+	properties, ok := entries[0][log.Properties].(log.Fields)
+	assert.True(t, ok)
+	assert.Equal(t, float64(123), properties["survey_id"])
+	assert.Equal(t, log.Fields{"title": "hello"}, properties["detail"])
+
+	assert.Len(t, recorder.Filter("save_failed"), 1)
+	assert.Len(t, recorder.Filter("surveySaved"), 1)
+	assert.Empty(t, recorder.Filter("other"))
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Entries())
+}
+
+func Test_Recorder_AssertLogged(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewWitCustomWriter(rsFields, recorder)
+
+	logger.Info("survey_saved", log.Fields{log.Message: "saved", "survey_id": 123})
+	logger.Error("save_failed", errors.New("disk full"))
+
+	recorder.AssertLogged(t, "survey_saved", log.InfoSev)
+	recorder.AssertLogged(t, "survey_saved", log.InfoSev, HasProperty("survey_id", 123), HasMessage("saved"))
+	recorder.AssertLogged(t, "save_failed", log.ErrorSev, HasError("disk full"), HasKey("exception.trace"))
+	recorder.AssertLogged(t, "save_failed", log.ErrorSev, HasField(log.User, "gavin"))
+	recorder.AssertNotLogged(t, "survey_saved", log.ErrorSev)
+	recorder.AssertLogged(t, "survey_saved", log.InfoSev, MatcherFunc("has trace", func(entry log.Fields) bool {
+		return entry[log.TraceID] == "1-2-3"
+	}))
+}
+
+func Test_Recorder_AssertLogged_Fails(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewWitCustomWriter(rsFields, recorder)
+	logger.Info("survey_saved", log.Fields{"survey_id": 123})
+
+	ft := &fakeT{}
+	assert.False(t, recorder.AssertLogged(ft, "survey_saved", log.InfoSev, HasProperty("survey_id", 456)))
+	assert.False(t, recorder.AssertLogged(ft, "survey_deleted", log.InfoSev))
+	assert.False(t, recorder.AssertNotLogged(ft, "survey_saved", log.InfoSev))
+	assert.Len(t, ft.failures, 3)
+	assert.Contains(t, ft.failures[0], "properties.survey_id=456")
+	assert.Contains(t, ft.failures[0], "\"survey_id\":123")
+}
+
+func Test_Recorder_Arrays(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewWitCustomWriter(rsFields, recorder)
+
+	cause := errors.New("disk full")
+	logger.Error("save_failed", fmt.Errorf("couldn't save: %w", cause), log.Fields{"items": []log.Fields{{"id": 1}}})
+
+	entry := recorder.Entries()[0]
+	causes := entry[log.Exception].(log.Fields)["causes"].([]interface{})
+	assert.Equal(t, "disk full", causes[0].(log.Fields)["error"])
+
+	recorder.AssertLogged(t, "save_failed", log.ErrorSev, HasField("exception.causes.0.error", "disk full"), HasProperty("items.0.id", 1))
+	recorder.AssertLogged(t, "save_failed", log.ErrorSev, HasProperty("items", []log.Fields{{"id": 1}}))
+	recorder.AssertNotLogged(t, "save_failed", log.ErrorSev, HasKey("exception.causes.1"))
+	recorder.AssertNotLogged(t, "save_failed", log.ErrorSev, HasKey("exception.causes.first"))
+}
+
+func Test_Recorder_Undecoded(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewWitCustomWriter(rsFields, recorder)
+	logger.Info("survey_saved")
+	recorder.record("not json")
+
+	assert.Equal(t, []string{"not json"}, recorder.Undecoded())
+	assert.Len(t, recorder.Entries(), 1)
+
+	ft := &fakeT{}
+	assert.False(t, recorder.AssertLogged(ft, "survey_saved", log.InfoSev))
+	assert.False(t, recorder.AssertNotLogged(ft, "survey_deleted", log.InfoSev))
+	assert.Len(t, ft.failures, 2)
+	assert.Contains(t, ft.failures[0], "not json")
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Undecoded())
+}
+
+func Test_Recorder_Parallel(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewWitCustomWriter(rsFields, recorder)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("parallel_event")
+			_ = recorder.Entries()
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, recorder.Filter("parallel_event"), 10)
+}
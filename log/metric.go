@@ -0,0 +1,290 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cultureamp/glamplify/env"
+)
+
+// MetricUnit is the unit of a CloudWatch metric
+type MetricUnit string
+
+const (
+	// Seconds = "Seconds"
+	Seconds MetricUnit = "Seconds"
+	// Microseconds = "Microseconds"
+	Microseconds MetricUnit = "Microseconds"
+	// Milliseconds = "Milliseconds"
+	Milliseconds MetricUnit = "Milliseconds"
+	// Bytes = "Bytes"
+	Bytes MetricUnit = "Bytes"
+	// Kilobytes = "Kilobytes"
+	Kilobytes MetricUnit = "Kilobytes"
+	// Megabytes = "Megabytes"
+	Megabytes MetricUnit = "Megabytes"
+	// Gigabytes = "Gigabytes"
+	Gigabytes MetricUnit = "Gigabytes"
+	// Terabytes = "Terabytes"
+	Terabytes MetricUnit = "Terabytes"
+	// Bits = "Bits"
+	Bits MetricUnit = "Bits"
+	// Kilobits = "Kilobits"
+	Kilobits MetricUnit = "Kilobits"
+	// Megabits = "Megabits"
+	Megabits MetricUnit = "Megabits"
+	// Gigabits = "Gigabits"
+	Gigabits MetricUnit = "Gigabits"
+	// Terabits = "Terabits"
+	Terabits MetricUnit = "Terabits"
+	// Percent = "Percent"
+	Percent MetricUnit = "Percent"
+	// Count = "Count"
+	Count MetricUnit = "Count"
+	// BytesPerSecond = "Bytes/Second"
+	BytesPerSecond MetricUnit = "Bytes/Second"
+	// KilobytesPerSecond = "Kilobytes/Second"
+	KilobytesPerSecond MetricUnit = "Kilobytes/Second"
+	// MegabytesPerSecond = "Megabytes/Second"
+	MegabytesPerSecond MetricUnit = "Megabytes/Second"
+	// GigabytesPerSecond = "Gigabytes/Second"
+	GigabytesPerSecond MetricUnit = "Gigabytes/Second"
+	// TerabytesPerSecond = "Terabytes/Second"
+	TerabytesPerSecond MetricUnit = "Terabytes/Second"
+	// BitsPerSecond = "Bits/Second"
+	BitsPerSecond MetricUnit = "Bits/Second"
+	// KilobitsPerSecond = "Kilobits/Second"
+	KilobitsPerSecond MetricUnit = "Kilobits/Second"
+	// MegabitsPerSecond = "Megabits/Second"
+	MegabitsPerSecond MetricUnit = "Megabits/Second"
+	// GigabitsPerSecond = "Gigabits/Second"
+	GigabitsPerSecond MetricUnit = "Gigabits/Second"
+	// TerabitsPerSecond = "Terabits/Second"
+	TerabitsPerSecond MetricUnit = "Terabits/Second"
+	// CountPerSecond = "Count/Second"
+	CountPerSecond MetricUnit = "Count/Second"
+	// NoUnit = "None"
+	NoUnit MetricUnit = "None"
+)
+
+var metricUnits = map[MetricUnit]bool{
+	Seconds: true, Microseconds: true, Milliseconds: true,
+	Bytes: true, Kilobytes: true, Megabytes: true, Gigabytes: true, Terabytes: true,
+	Bits: true, Kilobits: true, Megabits: true, Gigabits: true, Terabits: true,
+	Percent: true, Count: true,
+	BytesPerSecond: true, KilobytesPerSecond: true, MegabytesPerSecond: true, GigabytesPerSecond: true, TerabytesPerSecond: true,
+	BitsPerSecond: true, KilobitsPerSecond: true, MegabitsPerSecond: true, GigabitsPerSecond: true, TerabitsPerSecond: true,
+	CountPerSecond: true, NoUnit: true,
+}
+
+const (
+	// MetricEvent is the event of entries written by Logger.Metric and Logger.Metrics
+	MetricEvent = "metric"
+
+	// EMF limits, see https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+	maxMetricsPerEntry      = 100
+	maxValuesPerMetric      = 100
+	maxMetricDimensions     = 30
+	maxMetricNameLength     = 255
+	maxDimensionValueLength = 1024
+
+	awsMetadata            = "_aws"
+	defaultMetricNamespace = "aws-embedded-metrics"
+)
+
+// Dimensions are the names and values CloudWatch aggregates a metric by, eg. Dimensions{"farm": "production"}
+type Dimensions map[string]string
+
+type metricDatum struct {
+	name   string
+	unit   MetricUnit
+	values []float64
+}
+
+// MetricSet is a group of metrics written as one CloudWatch Embedded Metric Format (EMF) entry.
+// Create one with Logger.Metrics or Segment.Metrics, Add the metrics, then Write it.
+type MetricSet struct {
+	logger     Logger
+	event      string
+	fields     Fields
+	namespace  string
+	dimensions Dimensions
+	metrics    []*metricDatum
+}
+
+// Metric writes a single metric as a CloudWatch Embedded Metric Format entry, so CloudWatch creates the metric
+// from the log line without a StatsD agent. The namespace is LOG_METRIC_NAMESPACE, or APP if that isn't set.
+// Metrics are written regardless of the level. An error is returned, and nothing written, if the metric is outside the EMF limits.
+func (logger Logger) Metric(name string, value float64, unit MetricUnit, dimensions Dimensions) (string, error) {
+	return logger.Metrics(dimensions).Add(name, value, unit).write()
+}
+
+// Metrics returns a MetricSet to write several metrics with the same dimensions in one entry
+// eg. logger.Metrics(log.Dimensions{"farm": "production"}).Add("surveys_sent", 3, log.Count).Add("send_time", 120, log.Milliseconds).Write()
+func (logger Logger) Metrics(dimensions Dimensions) *MetricSet {
+	return &MetricSet{
+		logger:     logger,
+		event:      MetricEvent,
+		fields:     Fields{},
+		namespace:  env.GetString(env.LogMetricNamespace, env.GetString(env.AppNameEnv, defaultMetricNamespace)),
+		dimensions: dimensions,
+	}
+}
+
+// Metric writes a single metric for this segment as a CloudWatch Embedded Metric Format entry
+func (segment *Segment) Metric(name string, value float64, unit MetricUnit, dimensions Dimensions) (string, error) {
+	return segment.Metrics(dimensions).Add(name, value, unit).write()
+}
+
+// Metrics returns a MetricSet to write several metrics for this segment in one entry
+func (segment *Segment) Metrics(dimensions Dimensions) *MetricSet {
+	set := segment.logger.Metrics(dimensions)
+	set.event = segment.event
+	set.fields = segment.fields
+	return set
+}
+
+// Namespace sets the CloudWatch namespace of the metrics
+func (set *MetricSet) Namespace(namespace string) *MetricSet {
+	set.namespace = namespace
+	return set
+}
+
+// Add adds a metric to the set. Adding the same metric again adds another value, which CloudWatch aggregates.
+func (set *MetricSet) Add(name string, value float64, unit MetricUnit) *MetricSet {
+	name = snakeCase(name)
+	for _, metric := range set.metrics {
+		if metric.name == name {
+			metric.values = append(metric.values, value)
+			if metric.unit != unit {
+				// reported by Write
+				metric.unit = ""
+			}
+			return set
+		}
+	}
+
+	set.metrics = append(set.metrics, &metricDatum{name: name, unit: unit, values: []float64{value}})
+	return set
+}
+
+// Write writes the metrics as one entry with an "_aws" block describing them, and the metric and dimension values as top level keys.
+// An error is returned, and nothing written, if the metrics are outside the EMF limits.
+func (set *MetricSet) Write() (string, error) {
+	return set.write()
+}
+
+// write is called directly by the public methods, so the caller is always the same number of frames up
+func (set *MetricSet) write() (string, error) {
+	event := snakeCase(set.event)
+	caller := set.logger.sysValues.getCaller(callerSkipFrames)
+	system, properties := set.logger.newEntry(set.logger.rsFields, event, nil, InfoSev, caller.loc, set.fields)
+
+	metadata, err := set.emf(system)
+	if err != nil {
+		return "", err
+	}
+	system[awsMetadata] = metadata
+
+	// metrics aren't logs, so write them regardless of the level or sampling
+	return writeFields(set.logger.writer, true, InfoSev, system, properties), nil
+}
+
+// emf validates the metrics and adds their values to system, returning the "_aws" metadata
+func (set *MetricSet) emf(system Fields) (map[string]interface{}, error) {
+	if set.namespace == "" {
+		return nil, errors.New("metric namespace is empty")
+	}
+	if len(set.metrics) == 0 {
+		return nil, errors.New("no metrics to write")
+	}
+	if len(set.metrics) > maxMetricsPerEntry {
+		return nil, fmt.Errorf("%d metrics is more than the limit of %d per entry", len(set.metrics), maxMetricsPerEntry)
+	}
+	if len(set.dimensions) > maxMetricDimensions {
+		return nil, fmt.Errorf("%d dimensions is more than the limit of %d", len(set.dimensions), maxMetricDimensions)
+	}
+
+	dimensions := make([]string, 0, len(set.dimensions))
+	for name, value := range set.dimensions {
+		name = snakeCase(name)
+		if err := validateMetricName("dimension", name); err != nil {
+			return nil, err
+		}
+		if value == "" || len(value) > maxDimensionValueLength {
+			return nil, fmt.Errorf("dimension '%s' value must be between 1 and %d characters", name, maxDimensionValueLength)
+		}
+		// eg. "app" or "farm" are already in the entry, so can be used as a dimension if the value is the same (or wasn't set)
+		if existing, found := system[name]; found && existing != "" && existing != value {
+			return nil, fmt.Errorf("dimension '%s' has a different value to the entry's '%s'", name, name)
+		}
+		system[name] = value
+		dimensions = append(dimensions, name)
+	}
+	sort.Strings(dimensions)
+
+	metrics := make([]map[string]interface{}, 0, len(set.metrics))
+	for _, metric := range set.metrics {
+		if err := validateMetric(metric); err != nil {
+			return nil, err
+		}
+		if _, found := system[metric.name]; found {
+			return nil, fmt.Errorf("metric '%s' has the same name as a key in the entry", metric.name)
+		}
+
+		if len(metric.values) == 1 {
+			system[metric.name] = metric.values[0]
+		} else {
+			system[metric.name] = metric.values
+		}
+		metrics = append(metrics, map[string]interface{}{
+			"Name": metric.name,
+			"Unit": string(metric.unit),
+		})
+	}
+
+	return map[string]interface{}{
+		"Timestamp": time.Now().UnixMilli(),
+		"CloudWatchMetrics": []map[string]interface{}{
+			{
+				"Namespace":  set.namespace,
+				"Dimensions": [][]string{dimensions},
+				"Metrics":    metrics,
+			},
+		},
+	}, nil
+}
+
+func validateMetric(metric *metricDatum) error {
+	if err := validateMetricName("metric", metric.name); err != nil {
+		return err
+	}
+	if metric.unit == "" {
+		return fmt.Errorf("metric '%s' was added with different units", metric.name)
+	}
+	if !metricUnits[metric.unit] {
+		return fmt.Errorf("metric '%s' has unknown unit '%s'", metric.name, metric.unit)
+	}
+	if len(metric.values) > maxValuesPerMetric {
+		return fmt.Errorf("metric '%s' has %d values, more than the limit of %d", metric.name, len(metric.values), maxValuesPerMetric)
+	}
+	for _, value := range metric.values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("metric '%s' value must be a finite number", metric.name)
+		}
+	}
+	return nil
+}
+
+func validateMetricName(kind string, name string) error {
+	if name == "" || len(name) > maxMetricNameLength {
+		return fmt.Errorf("%s name must be between 1 and %d characters", kind, maxMetricNameLength)
+	}
+	if name == awsMetadata {
+		return fmt.Errorf("%s name '%s' is reserved", kind, name)
+	}
+	return nil
+}
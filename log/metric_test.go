@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cultureamp/glamplify/env"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Metric_EMF(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+
+	str, err := logger.Metric("SurveysSent", 3, Count, Dimensions{"survey_type": "engagement"})
+	assert.Nil(t, err)
+	assert.Equal(t, str+"\n", memBuffer.String())
+
+	entry := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(str), &entry))
+
+	assert.Equal(t, MetricEvent, entry[Event])
+	assert.Equal(t, InfoSev, entry[Severity])
+	assert.Equal(t, float64(3), entry["surveys_sent"])
+	assert.Equal(t, "engagement", entry["survey_type"])
+	assert.Equal(t, "hooli", entry[Customer])
+
+	aws := entry["_aws"].(map[string]interface{})
+	assert.NotZero(t, aws["Timestamp"])
+	directives := aws["CloudWatchMetrics"].([]interface{})
+	assert.Len(t, directives, 1)
+	directive := directives[0].(map[string]interface{})
+	assert.NotEmpty(t, directive["Namespace"])
+	assert.Equal(t, []interface{}{[]interface{}{"survey_type"}}, directive["Dimensions"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"Name": "surveys_sent", "Unit": "Count"}}, directive["Metrics"])
+}
+
+func Test_Metric_Namespace(t *testing.T) {
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}))
+
+	os.Setenv(env.LogMetricNamespace, "Engagement/Surveys")
+	defer os.Unsetenv(env.LogMetricNamespace)
+
+	str, err := logger.Metric("surveys_sent", 1, Count, nil)
+	assert.Nil(t, err)
+	assert.Contains(t, str, "\"Namespace\":\"Engagement/Surveys\"")
+	assert.Contains(t, str, "\"Dimensions\":[[]]")
+
+	str, err = logger.Metrics(nil).Namespace("Custom").Add("surveys_sent", 1, Count).Write()
+	assert.Nil(t, err)
+	assert.Contains(t, str, "\"Namespace\":\"Custom\"")
+}
+
+func Test_Metric_Set(t *testing.T) {
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}))
+
+	str, err := logger.Metrics(Dimensions{"farm": "production", "survey_type": "engagement"}).
+		Add("surveys_sent", 3, Count).
+		Add("send_time", 120, Milliseconds).
+		Add("send_time", 80, Milliseconds).
+		Write()
+	assert.Nil(t, err)
+	assert.Contains(t, str, "\"surveys_sent\":3")
+	assert.Contains(t, str, "\"send_time\":[120,80]")
+	assert.Contains(t, str, "\"farm\":\"production\"")
+	assert.Contains(t, str, "\"Dimensions\":[[\"farm\",\"survey_type\"]]")
+	assert.Contains(t, str, "{\"Name\":\"surveys_sent\",\"Unit\":\"Count\"}")
+	assert.Contains(t, str, "{\"Name\":\"send_time\",\"Unit\":\"Milliseconds\"}")
+}
+
+func Test_Metric_Segment(t *testing.T) {
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}))
+
+	str, err := logger.Event("survey_sent").Fields(Fields{"survey": "abc"}).Metric("send_time", 1.5, Seconds, nil)
+	assert.Nil(t, err)
+	assert.Contains(t, str, "\"event\":\"survey_sent\"")
+	assert.Contains(t, str, "\"survey\":\"abc\"")
+	assert.Contains(t, str, "\"send_time\":1.5")
+	assert.Contains(t, str, "\"Unit\":\"Seconds\"")
+
+	str, err = logger.Event("survey_sent").Metrics(nil).Add("a", 1, Count).Add("b", 2, Count).Write()
+	assert.Nil(t, err)
+	assert.Contains(t, str, "\"event\":\"survey_sent\"")
+	assert.Contains(t, str, "\"a\":1")
+	assert.Contains(t, str, "\"b\":2")
+}
+
+func Test_Metric_IgnoresLevel(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = ErrorSev
+	}))
+
+	str, err := logger.Metric("surveys_sent", 1, Count, nil)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, str)
+	assert.Contains(t, memBuffer.String(), "\"surveys_sent\":1")
+}
+
+func Test_Metric_Invalid(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+
+	tooManyDimensions := Dimensions{}
+	for i := 0; i <= maxMetricDimensions; i++ {
+		tooManyDimensions[fmt.Sprintf("dim_%d", i)] = "x"
+	}
+	tooManyMetrics := logger.Metrics(nil)
+	for i := 0; i <= maxMetricsPerEntry; i++ {
+		tooManyMetrics.Add(fmt.Sprintf("metric_%d", i), 1, Count)
+	}
+	tooManyValues := logger.Metrics(nil)
+	for i := 0; i <= maxValuesPerMetric; i++ {
+		tooManyValues.Add("metric", 1, Count)
+	}
+
+	tests := []struct {
+		name   string
+		set    *MetricSet
+		reason string
+	}{
+		{"unit", logger.Metrics(nil).Add("m", 1, "Furlongs"), "unknown unit 'Furlongs'"},
+		{"mixed units", logger.Metrics(nil).Add("m", 1, Count).Add("m", 1, Seconds), "different units"},
+		{"nan", logger.Metrics(nil).Add("m", math.NaN(), Count), "finite"},
+		{"inf", logger.Metrics(nil).Add("m", math.Inf(1), Count), "finite"},
+		{"empty name", logger.Metrics(nil).Add("", 1, Count), "metric name must be between 1 and 255"},
+		{"long name", logger.Metrics(nil).Add(strings.Repeat("m", 256), 1, Count), "metric name must be between 1 and 255"},
+		{"reserved", logger.Metrics(nil).Add("_aws", 1, Count), "reserved"},
+		{"system key", logger.Metrics(nil).Add("customer", 1, Count), "same name as a key"},
+		{"no metrics", logger.Metrics(nil), "no metrics"},
+		{"no namespace", logger.Metrics(nil).Namespace("").Add("m", 1, Count), "namespace"},
+		{"too many metrics", tooManyMetrics, "limit of 100"},
+		{"too many values", tooManyValues, "limit of 100"},
+		{"too many dimensions", logger.Metrics(tooManyDimensions).Add("m", 1, Count), "limit of 30"},
+		{"empty dimension", logger.Metrics(Dimensions{"farm": ""}).Add("m", 1, Count), "between 1 and 1024"},
+		{"dimension clash", logger.Metrics(Dimensions{"customer": "initech"}).Add("m", 1, Count), "different value"},
+	}
+
+	for _, tt := range tests {
+		str, err := tt.set.Write()
+		assert.Empty(t, str, tt.name)
+		if assert.NotNil(t, err, tt.name) {
+			assert.Contains(t, err.Error(), tt.reason, tt.name)
+		}
+	}
+	assert.Empty(t, memBuffer.String())
+}
+
+func Test_Metric_DimensionFromEntry(t *testing.T) {
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}))
+
+	str, err := logger.Metric("m", 1, Count, Dimensions{"customer": "hooli"})
+	assert.Nil(t, err)
+	assert.Contains(t, str, "\"Dimensions\":[[\"customer\"]]")
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"net/http"
+)
+
+// responseRecorder remembers the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// Flush lets handlers stream responses
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController get to the underlying http.ResponseWriter
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// Status returns the status written, or 200 if the handler didn't write one
+func (rr *responseRecorder) Status() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
+
+// RequestBufferMiddleware adds a RequestBuffer to each request's context. Loggers created from the request
+// (eg. NewFromRequest) hold the entries that would not otherwise be written, and write them if the request
+// logs an ERROR or FATAL, responds with a 5xx or panics. Otherwise they are discarded.
+func RequestBufferMiddleware(next http.Handler, configure ...func(*RequestBufferConfig)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buffer := NewRequestBuffer(configure...)
+		rr := newResponseRecorder(w)
+
+		defer func() {
+			if p := recover(); p != nil {
+				buffer.Flush()
+				panic(p)
+			}
+
+			if rr.Status() >= http.StatusInternalServerError {
+				buffer.Flush()
+			} else {
+				buffer.Discard()
+			}
+		}()
+
+		next.ServeHTTP(rr, r.WithContext(AddRequestBuffer(r.Context(), buffer)))
+	})
+}
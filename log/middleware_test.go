@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RequestBufferMiddleware(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = WarnSev
+	})
+
+	handler := RequestBufferMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := NewFromRequestWithCustomWriter(r, writer)
+		logger.Info("handling_" + r.URL.Query().Get("name"))
+
+		switch r.URL.Query().Get("fail") {
+		case "error":
+			logger.Error("handler_failed", errors.New("failed"))
+		case "status":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "panic":
+			panic("handler panicked")
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?name=ok", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, memBuffer.String())
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?name=error&fail=error", nil))
+	assert.Contains(t, memBuffer.String(), "\"event\":\"handling_error\"")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?name=status&fail=status", nil))
+	assert.Contains(t, memBuffer.String(), "\"event\":\"handling_status\"")
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?name=panic&fail=panic", nil))
+	})
+	assert.Contains(t, memBuffer.String(), "\"event\":\"handling_panic\"")
+	assert.NotContains(t, memBuffer.String(), "\"event\":\"handling_ok\"")
+}
+
+func Test_ResponseRecorder(t *testing.T) {
+	rr := newResponseRecorder(httptest.NewRecorder())
+	assert.Equal(t, http.StatusOK, rr.Status())
+
+	rr.WriteHeader(http.StatusNotFound)
+	rr.WriteHeader(http.StatusOK)
+	n, err := rr.Write([]byte("missing"))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, http.StatusNotFound, rr.Status())
+	assert.Equal(t, 7, rr.bytes)
+	assert.NotNil(t, rr.Unwrap())
+	rr.Flush()
+}
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"context"
+	systemLog "log"
+	"runtime/debug"
+)
+
+// Sink is one of the writers a MultiWriter sends every entry to
+type Sink struct {
+	// Writer to send entries to, eg. log.NewWriter() or datadog.NewDataDogWriter()
+	Writer Writer
+	// Level is the minimum severity sent to this sink. Empty leaves it up to the Writer.
+	Level string
+	// OmitEmpty removes empty string values before they are sent to this sink
+	OmitEmpty bool
+}
+
+// MultiWriter sends every entry to several sinks (eg. stdout and Data Dog), each with its own level.
+// A sink that panics is recovered so it can't stop the others being written to. Sinks are written to one after
+// the other, so a sink that blocks holds up the rest: wrap slow or remote sinks in an AsyncWriter.
+type MultiWriter struct {
+	sinks    []Sink
+	leveller *Leveller
+}
+
+// NewMultiWriter creates a new MultiWriter for the given sinks. Sinks without a Writer are ignored.
+func NewMultiWriter(sinks ...Sink) *MultiWriter {
+	valid := make([]Sink, 0, len(sinks))
+	for _, sink := range sinks {
+		if sink.Writer != nil {
+			valid = append(valid, sink)
+		}
+	}
+
+	return &MultiWriter{
+		sinks:    valid,
+		leveller: NewLevelMap(),
+	}
+}
+
+// WriteFields sends the entry to every sink and returns the json string from the first sink written to
+func (writer *MultiWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	json := ""
+	for _, sink := range writer.sinks {
+		if !writer.sinkAllows(sink, sev) {
+			continue
+		}
+
+		result := writer.writeSink(sink, sev, system, false, fields...)
+		if json == "" {
+			json = result
+		}
+	}
+
+	if json == "" {
+		json = writer.toJSON(system, fields...)
+	}
+	return json
+}
+
+// ForceWriteFields sends the entry to every sink regardless of their levels and returns the json string from the first sink
+func (writer *MultiWriter) ForceWriteFields(sev string, system Fields, fields ...Fields) string {
+	json := ""
+	for _, sink := range writer.sinks {
+		result := writer.writeSink(sink, sev, system, true, fields...)
+		if json == "" {
+			json = result
+		}
+	}
+
+	if json == "" {
+		json = writer.toJSON(system, fields...)
+	}
+	return json
+}
+
+// IsEnabled returns true if the sev is enabled for any sink, false otherwise
+func (writer *MultiWriter) IsEnabled(sev string) bool {
+	for _, sink := range writer.sinks {
+		if writer.sinkAllows(sink, sev) && sink.Writer.IsEnabled(sev) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redactor returns the Redactor of the first sink that has one, otherwise the default Redactor
+func (writer *MultiWriter) Redactor() *Redactor {
+	for _, sink := range writer.sinks {
+		if rw, ok := sink.Writer.(RedactingWriter); ok {
+			return rw.Redactor()
+		}
+	}
+
+	return defaultRedactor
+}
+
+// FatalHandler returns the FatalHandler of the first sink that has one, or nil for the default
+func (writer *MultiWriter) FatalHandler() FatalHandler {
+	for _, sink := range writer.sinks {
+		if fw, ok := sink.Writer.(fatalWriter); ok && fw.FatalHandler() != nil {
+			return fw.FatalHandler()
+		}
+	}
+	return nil
+}
+
+// Flush waits until every sink that queues entries (eg. AsyncWriter) has written them, or the ctx is done
+func (writer *MultiWriter) Flush(ctx context.Context) error {
+	for _, sink := range writer.sinks {
+		if f, ok := sink.Writer.(flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (writer *MultiWriter) isEnabledFor(scope logScope, sev string) bool {
+	for _, sink := range writer.sinks {
+		if !writer.sinkAllows(sink, sev) {
+			continue
+		}
+
+		if sw, ok := sink.Writer.(scopedWriter); ok {
+			if sw.isEnabledFor(scope, sev) {
+				return true
+			}
+		} else if sink.Writer.IsEnabled(sev) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (writer *MultiWriter) checkEntry(event string, system Fields, properties Fields) {
+	for _, sink := range writer.sinks {
+		if ec, ok := sink.Writer.(entryChecker); ok {
+			ec.checkEntry(event, system, properties)
+		}
+	}
+}
+
+func (writer *MultiWriter) sinkAllows(sink Sink, sev string) bool {
+	return sink.Level == "" || writer.leveller.ShouldLogSeverity(sink.Level, sev)
+}
+
+func (writer *MultiWriter) writeSink(sink Sink, sev string, system Fields, force bool, fields ...Fields) (json string) {
+	defer func() {
+		if r := recover(); r != nil {
+			systemLog.Printf("log sink %T panicked: %v, stacktrace: %s", sink.Writer, r, string(debug.Stack()))
+		}
+	}()
+
+	// each sink gets its own copy as writers add to (or redact) the system fields and properties
+	system = system.clone().omitEmpty(sink.OmitEmpty)
+	properties := Fields{}.Merge(fields...).clone().omitEmpty(sink.OmitEmpty)
+
+	return writeFields(sink.Writer, force, sev, system, properties)
+}
+
+func (writer *MultiWriter) toJSON(system Fields, fields ...Fields) string {
+	entry := system.Merge()
+	merged := Fields{}
+	properties := merged.Merge(fields...)
+	if len(properties) > 0 {
+		entry[Properties] = properties
+	}
+
+	return entry.ToSnakeCase().ToJSON(false)
+}
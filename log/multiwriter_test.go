@@ -0,0 +1,137 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type panicWriter struct{}
+
+func (writer panicWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	panic("sink is broken")
+}
+
+func (writer panicWriter) IsEnabled(sev string) bool {
+	return true
+}
+
+func Test_MultiWriter_Levels(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	remote := &bytes.Buffer{}
+	writer := NewMultiWriter(
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = stdout })},
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = remote }), Level: WarnSev},
+	)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	json := logger.Info("info_event")
+	assert.Contains(t, json, "\"event\":\"info_event\"")
+	assert.Contains(t, stdout.String(), "\"event\":\"info_event\"")
+	assert.Empty(t, remote.String())
+
+	logger.Warn("warn_event")
+	assert.Contains(t, stdout.String(), "\"event\":\"warn_event\"")
+	assert.Contains(t, remote.String(), "\"event\":\"warn_event\"")
+}
+
+func Test_MultiWriter_IsEnabled(t *testing.T) {
+	writer := NewMultiWriter(
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Level = ErrorSev })},
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Level = DebugSev }), Level: WarnSev},
+	)
+
+	assert.False(t, writer.IsEnabled(InfoSev))
+	assert.True(t, writer.IsEnabled(WarnSev))
+	assert.True(t, writer.IsEnabled(ErrorSev))
+	assert.False(t, NewMultiWriter().IsEnabled(ErrorSev))
+}
+
+func Test_MultiWriter_OmitEmpty(t *testing.T) {
+	full := &bytes.Buffer{}
+	omitted := &bytes.Buffer{}
+	writer := NewMultiWriter(
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = full })},
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = omitted }), OmitEmpty: true},
+	)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("omit_event", Fields{"empty": ""})
+	assert.Contains(t, full.String(), "\"empty\":\"\"")
+	assert.NotContains(t, omitted.String(), "\"empty\"")
+	assert.Contains(t, omitted.String(), "\"event\":\"omit_event\"")
+}
+
+func Test_MultiWriter_PanickingSink(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	writer := NewMultiWriter(
+		Sink{Writer: panicWriter{}},
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = stdout })},
+	)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	json := logger.Error("panic_event", errors.New("failed"))
+	assert.Contains(t, stdout.String(), "\"event\":\"panic_event\"")
+	assert.Contains(t, json, "\"event\":\"panic_event\"")
+}
+
+// changingWriter changes the entry it is given, like a writer that redacts or adds fields
+type changingWriter struct{}
+
+func (writer changingWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	system[Product] = "changed"
+	system[Exception].(Fields)["error"] = "changed"
+	for _, f := range fields {
+		f["survey_id"] = "changed"
+	}
+	return ""
+}
+
+func (writer changingWriter) IsEnabled(sev string) bool {
+	return true
+}
+
+func Test_MultiWriter_CopiesEntry(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	writer := NewMultiWriter(
+		Sink{Writer: changingWriter{}},
+		Sink{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = stdout })},
+	)
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	properties := Fields{"survey_id": "abc"}
+	logger.Error("copy_event", errors.New("failed"), properties)
+	assert.Contains(t, stdout.String(), "\"survey_id\":\"abc\"")
+	assert.Contains(t, stdout.String(), "\"error\":\"failed\"")
+	assert.NotContains(t, stdout.String(), "changed")
+	assert.Equal(t, "abc", properties["survey_id"])
+}
+
+func Test_MultiWriter_NoSinkWritten(t *testing.T) {
+	writer := NewMultiWriter(
+		Sink{Writer: NewWriter(), Level: ErrorSev},
+	)
+	logger := NewWitCustomWriter(rsFields, writer)
+	assert.Empty(t, logger.Info("quiet_event"))
+
+	json := writer.WriteFields(InfoSev, Fields{Event: "quiet_event"}, Fields{"count": 1})
+	assert.Contains(t, json, "\"event\":\"quiet_event\"")
+	assert.Contains(t, json, "\"count\":1")
+}
+
+func Test_MultiWriter_Flush(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	async := NewAsyncWriter(func(conf *AsyncWriterConfig) { conf.Output = stdout })
+	defer async.Close()
+	writer := NewMultiWriter(Sink{Writer: async})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("flush_event")
+	err := writer.Flush(context.Background())
+	assert.Nil(t, err)
+	assert.Contains(t, stdout.String(), "\"event\":\"flush_event\"")
+	assert.Same(t, async.Redactor(), writer.Redactor())
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"context"
+)
+
+// nextWriter is embedded by writers that sit in front of another Writer (eg. SamplingWriter),
+// and passes everything they don't change on to the next writer
+type nextWriter struct {
+	next Writer
+}
+
+// ForceWriteFields writes the entry to the next writer regardless of its level
+func (writer nextWriter) ForceWriteFields(sev string, system Fields, fields ...Fields) string {
+	return writeFields(writer.next, true, sev, system, fields...)
+}
+
+// IsEnabled returns true if the sev is enabled for the next writer, false otherwise
+func (writer nextWriter) IsEnabled(sev string) bool {
+	return writer.next.IsEnabled(sev)
+}
+
+// Redactor returns the Redactor of the next writer, or the default Redactor
+func (writer nextWriter) Redactor() *Redactor {
+	if rw, ok := writer.next.(RedactingWriter); ok {
+		return rw.Redactor()
+	}
+	return defaultRedactor
+}
+
+// FatalHandler returns the FatalHandler of the next writer, or nil for the default
+func (writer nextWriter) FatalHandler() FatalHandler {
+	if fw, ok := writer.next.(fatalWriter); ok {
+		return fw.FatalHandler()
+	}
+	return nil
+}
+
+// Flush flushes the next writer, if it queues entries
+func (writer nextWriter) Flush(ctx context.Context) error {
+	if f, ok := writer.next.(flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+func (writer nextWriter) isEnabledFor(scope logScope, sev string) bool {
+	if sw, ok := writer.next.(scopedWriter); ok {
+		return sw.isEnabledFor(scope, sev)
+	}
+	return writer.next.IsEnabled(sev)
+}
+
+func (writer nextWriter) checkEntry(event string, system Fields, properties Fields) {
+	if ec, ok := writer.next.(entryChecker); ok {
+		ec.checkEntry(event, system, properties)
+	}
+}
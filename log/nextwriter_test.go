@@ -0,0 +1,41 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NextWriter_Forwards(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	redactor := NewRedactor()
+	inner := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = WarnSev
+		conf.Redactor = redactor
+	})
+	defer inner.Close()
+	dedupe := NewDedupeWriter(inner)
+	defer dedupe.Close()
+
+	writers := map[string]Writer{
+		"sampling":      NewSamplingWriter(inner),
+		"tracesampling": NewTraceSamplingWriter(inner),
+		"validating":    NewValidatingWriter(inner),
+		"dedupe":        dedupe,
+	}
+
+	for name, writer := range writers {
+		assert.False(t, writer.IsEnabled(InfoSev), name)
+		assert.True(t, writer.IsEnabled(WarnSev), name)
+		assert.False(t, writer.(scopedWriter).isEnabledFor(logScope{event: "forward_event"}, InfoSev), name)
+		assert.Same(t, redactor, writer.(RedactingWriter).Redactor(), name)
+
+		memBuffer.Reset()
+		writer.(ForceWriter).ForceWriteFields(InfoSev, Fields{Event: "forced_" + name})
+		assert.Nil(t, writer.(flusher).Flush(context.Background()), name)
+		assert.Contains(t, memBuffer.String(), "\"event\":\"forced_"+name+"\"", name)
+	}
+}
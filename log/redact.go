@@ -0,0 +1,290 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/cultureamp/glamplify/env"
+	"github.com/cultureamp/glamplify/helper"
+)
+
+// DefaultRedactKeys are the key patterns whose values are always masked.
+// Patterns are matched against the snake_case version of the key and support '*' wildcards.
+// Token keys are matched as a whole word (eg. "access_token"), so "total_tokens" isn't masked.
+var DefaultRedactKeys = []string{
+	"*password*",
+	"*passwd*",
+	"*secret*",
+	"token",
+	"*_token",
+	"*api_key*",
+	"*apikey*",
+	"*private_key*",
+	"*credential*",
+	"authorization",
+	"cookie",
+	"set_cookie",
+}
+
+// DefaultRedactDetectors are the value patterns that are masked wherever they appear in a string value.
+var DefaultRedactDetectors = []*regexp.Regexp{
+	// email addresses
+	regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+	// JWTs (header.payload.signature, header and payload always start with '{"' base64 encoded)
+	regexp.MustCompile(`eyJ[a-zA-Z0-9_\-]+\.eyJ[a-zA-Z0-9_\-]+\.[a-zA-Z0-9_\-]*`),
+	// AWS access key ids
+	regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`),
+}
+
+// RedactorConfig for setting initial values for Redactor
+type RedactorConfig struct {
+	Enabled   bool
+	Keys      []string
+	Detectors []*regexp.Regexp
+}
+
+// Redactor masks sensitive values in Fields before they are written
+type Redactor struct {
+	enabled   bool
+	keys      []string
+	detectors []*regexp.Regexp
+
+	mutex     sync.RWMutex
+	sensitive map[string]bool
+}
+
+// NewRedactor creates a new Redactor. The optional configure func lets you add or replace the key patterns and value detectors.
+// By default redaction is enabled, can be turned off with LOG_REDACT=false and extra key patterns can be added with
+// LOG_REDACT_KEYS as a comma separated list (eg. "*ssn*,date_of_birth")
+func NewRedactor(configure ...func(*RedactorConfig)) *Redactor {
+	keys := append([]string{}, DefaultRedactKeys...)
+	keys = append(keys, splitList(env.GetString(env.LogRedactKeys, ""))...)
+
+	conf := RedactorConfig{
+		Enabled:   env.GetBool(env.LogRedact, true),
+		Keys:      keys,
+		Detectors: append([]*regexp.Regexp{}, DefaultRedactDetectors...),
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	redactor := &Redactor{
+		enabled:   conf.Enabled,
+		detectors: conf.Detectors,
+		sensitive: map[string]bool{},
+	}
+	for _, k := range conf.Keys {
+		redactor.keys = append(redactor.keys, strings.ToLower(helper.ToSnakeCase(k)))
+	}
+
+	return redactor
+}
+
+// Redact returns a copy of fields with sensitive values masked. Nested Fields are walked recursively.
+// If nothing needs masking the original fields are returned as is.
+func (redactor *Redactor) Redact(fields Fields) Fields {
+	if redactor == nil || !redactor.enabled || len(fields) == 0 {
+		return fields
+	}
+
+	redacted, _ := redactor.redactFields(fields)
+	return redacted
+}
+
+// redactErrors masks the detected values (eg. emails and tokens) in the error messages of the exception,
+// as errors often include the input that caused them. The exception must not be shared, as it is changed in place.
+func (redactor *Redactor) redactErrors(system Fields) {
+	if redactor == nil || !redactor.enabled {
+		return
+	}
+
+	exception, ok := system[Exception].(Fields)
+	if !ok {
+		return
+	}
+
+	redactor.redactError(exception)
+	if causes, ok := exception["causes"].([]Fields); ok {
+		for _, cause := range causes {
+			redactor.redactError(cause)
+		}
+	}
+}
+
+func (redactor *Redactor) redactError(fields Fields) {
+	if message, ok := fields["error"].(string); ok {
+		fields["error"], _ = redactor.redactString(message)
+	}
+}
+
+func (redactor *Redactor) redactFields(fields Fields) (Fields, bool) {
+	var copied Fields
+	for k, v := range fields {
+		rv, changed := redactor.redactValue(k, v)
+		if !changed {
+			continue
+		}
+
+		if copied == nil {
+			copied = make(Fields, len(fields))
+			for ck, cv := range fields {
+				copied[ck] = cv
+			}
+		}
+		copied[k] = rv
+	}
+
+	if copied == nil {
+		return fields, false
+	}
+	return copied, true
+}
+
+func (redactor *Redactor) redactValue(key string, value interface{}) (interface{}, bool) {
+	if value == nil {
+		return value, false
+	}
+
+	if redactor.isSensitiveKey(key) {
+		return helper.Redact(fmt.Sprintf("%v", value)), true
+	}
+
+	switch v := value.(type) {
+	case Fields:
+		return redactor.redactFields(v)
+	case map[string]interface{}:
+		return redactor.redactFields(v)
+	case string:
+		return redactor.redactString(v)
+	case []string:
+		return redactor.redactStrings(v)
+	case []interface{}:
+		return redactor.redactSlice(key, v)
+	case json.Marshaler:
+		// written with MarshalJSON, not as text (see serialize)
+		return value, false
+	case error:
+		return redactor.redactText(value, v.Error)
+	case fmt.Stringer:
+		return redactor.redactText(value, v.String)
+	}
+
+	return value, false
+}
+
+// redactText redacts the text an error or Stringer is written as. It is only replaced by the text if it changed.
+func (redactor *Redactor) redactText(value interface{}, text func() string) (rv interface{}, changed bool) {
+	defer func() {
+		// eg. a nil pointer with a value receiver, which serialize writes as a marker
+		if r := recover(); r != nil {
+			rv, changed = value, false
+		}
+	}()
+
+	if redacted, changed := redactor.redactString(text()); changed {
+		return redacted, true
+	}
+	return value, false
+}
+
+func (redactor *Redactor) redactString(s string) (string, bool) {
+	changed := false
+	for _, detector := range redactor.detectors {
+		if !detector.MatchString(s) {
+			continue
+		}
+		s = detector.ReplaceAllStringFunc(s, helper.Redact)
+		changed = true
+	}
+
+	return s, changed
+}
+
+func (redactor *Redactor) redactStrings(values []string) ([]string, bool) {
+	var copied []string
+	for i, v := range values {
+		rv, changed := redactor.redactString(v)
+		if !changed {
+			continue
+		}
+
+		if copied == nil {
+			copied = append([]string{}, values...)
+		}
+		copied[i] = rv
+	}
+
+	if copied == nil {
+		return values, false
+	}
+	return copied, true
+}
+
+func (redactor *Redactor) redactSlice(key string, values []interface{}) ([]interface{}, bool) {
+	var copied []interface{}
+	for i, v := range values {
+		rv, changed := redactor.redactValue(key, v)
+		if !changed {
+			continue
+		}
+
+		if copied == nil {
+			copied = append([]interface{}{}, values...)
+		}
+		copied[i] = rv
+	}
+
+	if copied == nil {
+		return values, false
+	}
+	return copied, true
+}
+
+// isSensitiveKey matches the key against the patterns, caching the result as the same keys are logged over and over
+func (redactor *Redactor) isSensitiveKey(key string) bool {
+	redactor.mutex.RLock()
+	sensitive, found := redactor.sensitive[key]
+	redactor.mutex.RUnlock()
+	if found {
+		return sensitive
+	}
+
+	sensitive = redactor.matchesKey(key)
+
+	redactor.mutex.Lock()
+	defer redactor.mutex.Unlock()
+
+	// don't grow forever if keys are generated at runtime
+	if len(redactor.sensitive) < maxCachedKeys {
+		redactor.sensitive[key] = sensitive
+	}
+	return sensitive
+}
+
+func (redactor *Redactor) matchesKey(key string) bool {
+	key = strings.ToLower(snakeCase(key))
+	for _, pattern := range redactor.keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
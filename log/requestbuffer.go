@@ -0,0 +1,117 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cultureamp/glamplify/env"
+)
+
+const defaultRequestBufferSize = 256
+
+// RequestBufferConfig for setting initial values for RequestBuffer
+type RequestBufferConfig struct {
+	// MaxEntries held before the oldest are dropped (LOG_REQUEST_BUFFER_SIZE, default 256)
+	MaxEntries int
+}
+
+type bufferedEntry struct {
+	writer     Writer
+	sev        string
+	system     Fields
+	properties Fields
+}
+
+// RequestBuffer holds the entries for a single request that the writer would not otherwise write (eg. DEBUG when the
+// level is INFO). If the request logs an ERROR or FATAL they are written first, so you get the detail for failing
+// requests without paying for it on successful ones. Otherwise they are discarded at the end of the request.
+// Loggers created with NewFromCtx or NewFromRequest use the RequestBuffer in the context, if there is one.
+type RequestBuffer struct {
+	mutex      sync.Mutex
+	entries    []bufferedEntry
+	maxEntries int
+	dropped    int
+}
+
+// NewRequestBuffer creates a new empty RequestBuffer
+func NewRequestBuffer(configure ...func(*RequestBufferConfig)) *RequestBuffer {
+	conf := RequestBufferConfig{
+		MaxEntries: env.GetInt(env.LogRequestBufferSize, defaultRequestBufferSize),
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if conf.MaxEntries <= 0 {
+		conf.MaxEntries = defaultRequestBufferSize
+	}
+
+	return &RequestBuffer{
+		maxEntries: conf.MaxEntries,
+	}
+}
+
+// AddRequestBuffer adds the RequestBuffer to the context
+func AddRequestBuffer(ctx context.Context, buffer *RequestBuffer) context.Context {
+	return context.WithValue(ctx, requestBufferCtx, buffer)
+}
+
+// GetRequestBuffer gets the RequestBuffer from the context
+func GetRequestBuffer(ctx context.Context) (*RequestBuffer, bool) {
+	buffer, ok := ctx.Value(requestBufferCtx).(*RequestBuffer)
+	return buffer, ok && buffer != nil
+}
+
+// Len returns the number of entries held
+func (buffer *RequestBuffer) Len() int {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return len(buffer.entries)
+}
+
+// Dropped returns the number of entries dropped because the buffer was full
+func (buffer *RequestBuffer) Dropped() int {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return buffer.dropped
+}
+
+// Flush writes all the held entries, oldest first, regardless of the level (see ForceWriter) and empties the buffer
+func (buffer *RequestBuffer) Flush() {
+	for _, entry := range buffer.take() {
+		writeFields(entry.writer, true, entry.sev, entry.system, entry.properties)
+	}
+}
+
+// Discard empties the buffer without writing the entries
+func (buffer *RequestBuffer) Discard() {
+	buffer.take()
+}
+
+func (buffer *RequestBuffer) add(writer Writer, sev string, system Fields, properties Fields) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if len(buffer.entries) >= buffer.maxEntries {
+		buffer.entries = buffer.entries[1:]
+		buffer.dropped++
+	}
+
+	buffer.entries = append(buffer.entries, bufferedEntry{
+		writer: writer,
+		sev:    sev,
+		system: system,
+		// copy as the caller may change the fields after logging
+		properties: properties.Merge(),
+	})
+}
+
+func (buffer *RequestBuffer) take() []bufferedEntry {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	entries := buffer.entries
+	buffer.entries = nil
+	return entries
+}
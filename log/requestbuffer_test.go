@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBufferedLogger(memBuffer *bytes.Buffer, buffer *RequestBuffer) *Logger {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = InfoSev
+	})
+	ctx := gcontext.AddRequestFields(context.Background(), rsFields)
+	ctx = AddRequestBuffer(ctx, buffer)
+	return NewFromCtxWithCustomerWriter(ctx, writer)
+}
+
+func Test_RequestBuffer_FlushOnError(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	buffer := NewRequestBuffer()
+	logger := newBufferedLogger(memBuffer, buffer)
+
+	assert.Empty(t, logger.Debug("debug_detail", Fields{"step": 1}))
+	logger.Info("info_event")
+	assert.Equal(t, 1, buffer.Len())
+	assert.NotContains(t, memBuffer.String(), "debug_detail")
+
+	logger.Error("request_failed", errors.New("failed"))
+	output := memBuffer.String()
+	assert.Contains(t, output, "\"event\":\"debug_detail\"")
+	assert.Contains(t, output, "\"step\":1")
+	assert.Less(t, strings.Index(output, "debug_detail"), strings.Index(output, "request_failed"))
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func Test_RequestBuffer_Discard(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	buffer := NewRequestBuffer()
+	logger := newBufferedLogger(memBuffer, buffer)
+
+	logger.Debug("debug_detail")
+	buffer.Discard()
+	buffer.Flush()
+	assert.Empty(t, memBuffer.String())
+}
+
+func Test_RequestBuffer_Cap(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	buffer := NewRequestBuffer(func(conf *RequestBufferConfig) {
+		conf.MaxEntries = 2
+	})
+	logger := newBufferedLogger(memBuffer, buffer)
+
+	logger.Debug("debug_1")
+	logger.Debug("debug_2")
+	logger.Debug("debug_3")
+	assert.Equal(t, 2, buffer.Len())
+	assert.Equal(t, 1, buffer.Dropped())
+
+	buffer.Flush()
+	assert.NotContains(t, memBuffer.String(), "debug_1")
+	assert.Contains(t, memBuffer.String(), "debug_2")
+	assert.Contains(t, memBuffer.String(), "debug_3")
+}
+
+func Test_RequestBuffer_CopiesFields(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	buffer := NewRequestBuffer()
+	logger := newBufferedLogger(memBuffer, buffer)
+
+	segment := logger.Event("segment_event")
+	segment.Debug("first")
+	segment.Debug("second")
+	buffer.Flush()
+
+	assert.Contains(t, memBuffer.String(), "\"message\":\"first\"")
+	assert.Contains(t, memBuffer.String(), "\"message\":\"second\"")
+}
+
+func Test_RequestBuffer_ForceWriters(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	buffer := NewRequestBuffer()
+	writer := NewTraceSamplingWriter(NewMultiWriter(Sink{
+		Writer: NewWriter(func(conf *WriterConfig) { conf.Output = memBuffer }),
+		Level:  ErrorSev,
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+		conf.KeepErrors = false
+	})
+	ctx := AddRequestBuffer(gcontext.AddRequestFields(context.Background(), rsFields), buffer)
+	logger := NewFromCtxWithCustomerWriter(ctx, writer)
+
+	logger.Info("info_event")
+	assert.Equal(t, 1, buffer.Len())
+
+	buffer.Flush()
+	assert.Contains(t, memBuffer.String(), "\"event\":\"info_event\"")
+}
+
+func Test_RequestBuffer_Ctx(t *testing.T) {
+	_, ok := GetRequestBuffer(context.Background())
+	assert.False(t, ok)
+
+	buffer := NewRequestBuffer()
+	found, ok := GetRequestBuffer(AddRequestBuffer(context.Background(), buffer))
+	assert.True(t, ok)
+	assert.Same(t, buffer, found)
+
+	assert.Nil(t, NewFromCtx(context.Background()).buffer)
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultSampleFirst      = 100
+	defaultSampleThereafter = 100
+	defaultSampleInterval   = time.Second
+)
+
+// SamplingWriterConfig for setting initial values for SamplingWriter
+type SamplingWriterConfig struct {
+	// First entries for each event and severity are written every Interval...
+	First int
+	// ...then 1 in every Thereafter
+	Thereafter int
+	Interval   time.Duration
+	// Exempt severities are never sampled. Default ERROR, FATAL and AUDIT.
+	Exempt []string
+}
+
+type sampleKey struct {
+	event    string
+	severity string
+}
+
+// SamplingWriter sits in front of another Writer and limits how many identical events are written.
+// For each event and severity the first N entries every interval are written, then 1 in every M.
+// Written entries get a "sample_rate" field (1 or M) so dashboards can re-weight counts.
+type SamplingWriter struct {
+	nextWriter
+	first      uint64
+	thereafter uint64
+	interval   time.Duration
+	exempt     map[string]bool
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	counts      map[sampleKey]uint64
+	now         func() time.Time
+}
+
+// NewSamplingWriter creates a new SamplingWriter in front of next.
+// By default the first 100 entries per second are written, then 1 in every 100.
+func NewSamplingWriter(next Writer, configure ...func(*SamplingWriterConfig)) *SamplingWriter {
+	conf := SamplingWriterConfig{
+		First:      defaultSampleFirst,
+		Thereafter: defaultSampleThereafter,
+		Interval:   defaultSampleInterval,
+		Exempt:     []string{ErrorSev, FatalSev, AuditSev},
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if conf.First < 0 {
+		conf.First = 0
+	}
+	if conf.Thereafter <= 0 {
+		conf.Thereafter = 1
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultSampleInterval
+	}
+
+	writer := &SamplingWriter{
+		nextWriter: nextWriter{next: next},
+		first:      uint64(conf.First),
+		thereafter: uint64(conf.Thereafter),
+		interval:   conf.Interval,
+		exempt:     map[string]bool{},
+		counts:     map[sampleKey]uint64{},
+		now:        time.Now,
+	}
+	for _, sev := range conf.Exempt {
+		writer.exempt[sev] = true
+	}
+
+	return writer
+}
+
+// WriteFields writes the entry to the next writer if it is sampled, and returns "" if it is not
+func (writer *SamplingWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	if writer.exempt[sev] {
+		return writer.next.WriteFields(sev, system, fields...)
+	}
+
+	event, _ := system[Event].(string)
+	rate, ok := writer.sample(sampleKey{event: event, severity: sev})
+	if !ok {
+		return ""
+	}
+
+	system[SampleRate] = rate
+	return writer.next.WriteFields(sev, system, fields...)
+}
+
+// sample returns the sample rate and true if the entry should be written
+func (writer *SamplingWriter) sample(key sampleKey) (uint64, bool) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	// start a new window, which also forgets events we haven't seen for a while
+	now := writer.now()
+	if now.Sub(writer.windowStart) >= writer.interval {
+		writer.windowStart = now
+		writer.counts = map[sampleKey]uint64{}
+	}
+
+	writer.counts[key]++
+	n := writer.counts[key]
+	if n <= writer.first {
+		return 1, true
+	}
+
+	return writer.thereafter, (n-writer.first)%writer.thereafter == 0
+}
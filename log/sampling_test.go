@@ -0,0 +1,107 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SamplingWriter_FirstThenEvery(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *SamplingWriterConfig) {
+		conf.First = 3
+		conf.Thereafter = 5
+		conf.Interval = time.Hour
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	written := 0
+	for i := 0; i < 23; i++ {
+		if logger.Info("busy_event") != "" {
+			written++
+		}
+	}
+
+	// 3 + every 5th of the remaining 20
+	assert.Equal(t, 7, written)
+	assert.Equal(t, 7, strings.Count(memBuffer.String(), "\"event\":\"busy_event\""))
+	assert.Equal(t, 3, strings.Count(memBuffer.String(), "\"sample_rate\":1,"))
+	assert.Equal(t, 4, strings.Count(memBuffer.String(), "\"sample_rate\":5,"))
+}
+
+func Test_SamplingWriter_PerEventAndSeverity(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *SamplingWriterConfig) {
+		conf.First = 1
+		conf.Thereafter = 1000
+		conf.Interval = time.Hour
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	assert.NotEmpty(t, logger.Info("event_a"))
+	assert.Empty(t, logger.Info("event_a"))
+	assert.NotEmpty(t, logger.Warn("event_a"))
+	assert.NotEmpty(t, logger.Info("event_b"))
+}
+
+func Test_SamplingWriter_Exempt(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *SamplingWriterConfig) {
+		conf.First = 0
+		conf.Thereafter = 1000
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	for i := 0; i < 5; i++ {
+		logger.Error("failed_event", errors.New("failed"))
+		logger.Audit("audit_event")
+	}
+
+	assert.Equal(t, 5, strings.Count(memBuffer.String(), "\"event\":\"failed_event\""))
+	assert.Equal(t, 5, strings.Count(memBuffer.String(), "\"event\":\"audit_event\""))
+	assert.NotContains(t, memBuffer.String(), SampleRate)
+}
+
+func Test_SamplingWriter_Interval(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *SamplingWriterConfig) {
+		conf.First = 1
+		conf.Thereafter = 1000
+		conf.Interval = time.Minute
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	writer.now = func() time.Time { return now }
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	assert.NotEmpty(t, logger.Info("busy_event"))
+	assert.Empty(t, logger.Info("busy_event"))
+
+	now = now.Add(time.Minute)
+	assert.NotEmpty(t, logger.Info("busy_event"))
+	assert.Empty(t, logger.Info("busy_event"))
+}
+
+func Test_SamplingWriter_Delegates(t *testing.T) {
+	next := NewWriter(func(conf *WriterConfig) {
+		conf.Level = WarnSev
+	})
+	writer := NewSamplingWriter(next)
+
+	assert.False(t, writer.IsEnabled(InfoSev))
+	assert.True(t, writer.IsEnabled(ErrorSev))
+	assert.Same(t, next.Redactor(), writer.Redactor())
+	assert.Nil(t, writer.Flush(context.Background()))
+}
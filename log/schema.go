@@ -0,0 +1,224 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// FieldType is the type of a property in an EventSchema
+type FieldType string
+
+const (
+	// AnyType allows any value
+	AnyType FieldType = "any"
+	// StringType allows strings
+	StringType FieldType = "string"
+	// IntType allows whole numbers
+	IntType FieldType = "int"
+	// FloatType allows any number
+	FloatType FieldType = "float"
+	// BoolType allows true or false
+	BoolType FieldType = "bool"
+	// ObjectType allows Fields, maps and structs
+	ObjectType FieldType = "object"
+	// ArrayType allows slices and arrays
+	ArrayType FieldType = "array"
+)
+
+var snakeCaseName = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// FieldSchema describes a property of an event
+type FieldSchema struct {
+	Type        FieldType `json:"type"`
+	Required    bool      `json:"required,omitempty"`
+	MaxLength   int       `json:"max_length,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// EventSchema describes the properties an event is logged with
+type EventSchema struct {
+	Event       string                 `json:"event"`
+	Description string                 `json:"description,omitempty"`
+	Fields      map[string]FieldSchema `json:"fields"`
+	// Strict events can't have properties that aren't in Fields
+	Strict bool `json:"strict,omitempty"`
+}
+
+// SchemaViolation is a way an entry doesn't match the conventions or its EventSchema
+type SchemaViolation struct {
+	Event  string `json:"event"`
+	Key    string `json:"key,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// String returns the violation as a sentence
+func (violation SchemaViolation) String() string {
+	if violation.Key == "" {
+		return fmt.Sprintf("event '%s' %s", violation.Event, violation.Reason)
+	}
+	return fmt.Sprintf("event '%s' key '%s' %s", violation.Event, violation.Key, violation.Reason)
+}
+
+// SchemaRegistry holds the EventSchema of the events a service logs
+type SchemaRegistry struct {
+	mutex   sync.RWMutex
+	schemas map[string]EventSchema
+}
+
+// NewSchemaRegistry creates a new SchemaRegistry and registers the schemas, panicking if any are invalid
+func NewSchemaRegistry(schemas ...EventSchema) *SchemaRegistry {
+	registry := &SchemaRegistry{
+		schemas: map[string]EventSchema{},
+	}
+	for _, schema := range schemas {
+		if err := registry.Register(schema); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
+}
+
+// Register adds the schema, returning an error if the event or its keys aren't snake_case or the event is already registered
+func (registry *SchemaRegistry) Register(schema EventSchema) error {
+	if !snakeCaseName.MatchString(schema.Event) {
+		return fmt.Errorf("event '%s' must be snake_case", schema.Event)
+	}
+	for key, field := range schema.Fields {
+		if !snakeCaseName.MatchString(key) {
+			return fmt.Errorf("event '%s' key '%s' must be snake_case", schema.Event, key)
+		}
+		switch field.Type {
+		case AnyType, StringType, IntType, FloatType, BoolType, ObjectType, ArrayType:
+		default:
+			return fmt.Errorf("event '%s' key '%s' has unknown type '%s'", schema.Event, key, field.Type)
+		}
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if _, found := registry.schemas[schema.Event]; found {
+		return fmt.Errorf("event '%s' is already registered", schema.Event)
+	}
+	registry.schemas[schema.Event] = schema
+	return nil
+}
+
+// Schema returns the EventSchema for the event, if it is registered
+func (registry *SchemaRegistry) Schema(event string) (EventSchema, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	schema, found := registry.schemas[event]
+	return schema, found
+}
+
+// Schemas returns every registered EventSchema, sorted by event
+func (registry *SchemaRegistry) Schemas() []EventSchema {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	schemas := make([]EventSchema, 0, len(registry.schemas))
+	for _, schema := range registry.schemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Event < schemas[j].Event
+	})
+	return schemas
+}
+
+// MarshalJSON writes the registry as {"events": [...]} so it can be published as documentation
+func (registry *SchemaRegistry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Events []EventSchema `json:"events"`
+	}{
+		Events: registry.Schemas(),
+	})
+}
+
+// Validate returns the ways the event and its properties don't match the snake_case conventions or the event's schema.
+// Pass the names as they were logged: they are checked as given, and snake_cased to find and check the schema.
+// Events without a schema only have their names checked.
+func (registry *SchemaRegistry) Validate(event string, properties Fields) []SchemaViolation {
+	var violations []SchemaViolation
+
+	if !snakeCaseName.MatchString(event) {
+		violations = append(violations, SchemaViolation{Event: event, Reason: "must be snake_case"})
+	}
+	for key := range properties {
+		if !snakeCaseName.MatchString(key) {
+			violations = append(violations, SchemaViolation{Event: event, Key: key, Reason: "must be snake_case"})
+		}
+	}
+
+	schema, found := registry.Schema(snakeCase(event))
+	if !found {
+		return sortViolations(violations)
+	}
+	properties = properties.ToSnakeCase()
+
+	for key, field := range schema.Fields {
+		value, ok := properties[key]
+		if !ok || value == nil {
+			if field.Required {
+				violations = append(violations, SchemaViolation{Event: event, Key: key, Reason: "is required"})
+			}
+			continue
+		}
+
+		if !isFieldType(value, field.Type) {
+			violations = append(violations, SchemaViolation{Event: event, Key: key, Reason: fmt.Sprintf("must be %s, not %T", field.Type, value)})
+			continue
+		}
+
+		if s, ok := value.(string); ok && field.MaxLength > 0 && len(s) > field.MaxLength {
+			violations = append(violations, SchemaViolation{Event: event, Key: key, Reason: fmt.Sprintf("is longer than %d characters", field.MaxLength)})
+		}
+	}
+
+	if schema.Strict {
+		for key := range properties {
+			if _, ok := schema.Fields[key]; !ok {
+				violations = append(violations, SchemaViolation{Event: event, Key: key, Reason: "is not in the schema"})
+			}
+		}
+	}
+
+	return sortViolations(violations)
+}
+
+func sortViolations(violations []SchemaViolation) []SchemaViolation {
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Key < violations[j].Key
+	})
+	return violations
+}
+
+func isFieldType(value interface{}, fieldType FieldType) bool {
+	kind := reflect.TypeOf(value).Kind()
+	if kind == reflect.Pointer {
+		kind = reflect.TypeOf(value).Elem().Kind()
+	}
+
+	switch fieldType {
+	case StringType:
+		return kind == reflect.String
+	case IntType:
+		return kind >= reflect.Int && kind <= reflect.Uint64
+	case FloatType:
+		return (kind >= reflect.Int && kind <= reflect.Uint64) || kind == reflect.Float32 || kind == reflect.Float64
+	case BoolType:
+		return kind == reflect.Bool
+	case ObjectType:
+		return kind == reflect.Map || kind == reflect.Struct
+	case ArrayType:
+		return kind == reflect.Slice || kind == reflect.Array
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var surveySavedSchema = EventSchema{
+	Event:       "survey_saved",
+	Description: "A survey was saved",
+	Fields: map[string]FieldSchema{
+		"survey_id": {Type: StringType, Required: true},
+		"title":     {Type: StringType, MaxLength: 10},
+		"questions": {Type: IntType},
+		"score":     {Type: FloatType},
+		"draft":     {Type: BoolType},
+		"tags":      {Type: ArrayType},
+		"detail":    {Type: ObjectType},
+		"extra":     {Type: AnyType},
+	},
+}
+
+func Test_SchemaRegistry_Register(t *testing.T) {
+	registry := NewSchemaRegistry(surveySavedSchema)
+
+	schema, ok := registry.Schema("survey_saved")
+	assert.True(t, ok)
+	assert.Equal(t, "A survey was saved", schema.Description)
+
+	assert.NotNil(t, registry.Register(surveySavedSchema))
+	assert.NotNil(t, registry.Register(EventSchema{Event: "SurveySaved"}))
+	assert.NotNil(t, registry.Register(EventSchema{Event: "survey__saved"}))
+	assert.NotNil(t, registry.Register(EventSchema{Event: "survey_deleted", Fields: map[string]FieldSchema{"surveyId": {Type: StringType}}}))
+	assert.NotNil(t, registry.Register(EventSchema{Event: "survey_deleted", Fields: map[string]FieldSchema{"survey_id": {Type: "uuid"}}}))
+	assert.Nil(t, registry.Register(EventSchema{Event: "survey_deleted"}))
+
+	assert.Panics(t, func() {
+		NewSchemaRegistry(EventSchema{Event: "Bad Event"})
+	})
+}
+
+func Test_SchemaRegistry_Validate(t *testing.T) {
+	registry := NewSchemaRegistry(surveySavedSchema)
+
+	assert.Empty(t, registry.Validate("survey_saved", Fields{
+		"survey_id": "123",
+		"title":     "short",
+		"questions": 10,
+		"score":     4.5,
+		"draft":     true,
+		"tags":      []string{"a"},
+		"detail":    Fields{"a": 1},
+		"extra":     struct{}{},
+		"unknown":   "allowed",
+	}))
+	assert.Empty(t, registry.Validate("survey_saved", Fields{"survey_id": "123", "score": 4}))
+	assert.Empty(t, registry.Validate("other_event", Fields{"anything": 1}))
+
+	violations := registry.Validate("survey_saved", Fields{
+		"title":     "much too long",
+		"questions": 1.5,
+		"draft":     "no",
+	})
+	assert.Equal(t, []SchemaViolation{
+		{Event: "survey_saved", Key: "draft", Reason: "must be bool, not string"},
+		{Event: "survey_saved", Key: "questions", Reason: "must be int, not float64"},
+		{Event: "survey_saved", Key: "survey_id", Reason: "is required"},
+		{Event: "survey_saved", Key: "title", Reason: "is longer than 10 characters"},
+	}, violations)
+	assert.Equal(t, "event 'survey_saved' key 'draft' must be bool, not string", violations[0].String())
+
+	violations = registry.Validate("survey.saved", nil)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "event 'survey.saved' must be snake_case", violations[0].String())
+
+	// names are checked as given, and snake_cased to check the schema
+	assert.Equal(t, []SchemaViolation{
+		{Event: "SurveySaved", Reason: "must be snake_case"},
+		{Event: "SurveySaved", Key: "surveyId", Reason: "must be snake_case"},
+	}, registry.Validate("SurveySaved", Fields{"surveyId": "123"}))
+}
+
+func Test_SchemaRegistry_Strict(t *testing.T) {
+	registry := NewSchemaRegistry(EventSchema{
+		Event:  "survey_deleted",
+		Strict: true,
+		Fields: map[string]FieldSchema{"survey_id": {Type: StringType}},
+	})
+
+	assert.Empty(t, registry.Validate("survey_deleted", Fields{"survey_id": "123"}))
+	assert.Equal(t, []SchemaViolation{
+		{Event: "survey_deleted", Key: "unknown", Reason: "is not in the schema"},
+	}, registry.Validate("survey_deleted", Fields{"unknown": 1}))
+}
+
+func Test_SchemaRegistry_JSON(t *testing.T) {
+	registry := NewSchemaRegistry(surveySavedSchema, EventSchema{Event: "account_created"})
+
+	bytes, err := json.Marshal(registry)
+	assert.Nil(t, err)
+
+	doc := struct {
+		Events []EventSchema `json:"events"`
+	}{}
+	assert.Nil(t, json.Unmarshal(bytes, &doc))
+	assert.Len(t, doc.Events, 2)
+	assert.Equal(t, "account_created", doc.Events[0].Event)
+	assert.Equal(t, surveySavedSchema, doc.Events[1])
+	assert.Contains(t, string(bytes), "\"max_length\":10")
+}
@@ -13,6 +13,12 @@ func (segment *Segment) Fields(fields ...Fields) *Segment {
 	return segment
 }
 
+// IsEnabled returns true if the given severity is enabled for this segment's event and the calling package
+func (segment *Segment) IsEnabled(severity string) bool {
+	scope := segment.logger.newLogScope(snakeCase(segment.event), callerPackage(1))
+	return segment.logger.isEnabledFor(scope, severity)
+}
+
 // Debug logs a debug message for this segment
 func (segment *Segment) Debug(message string) string {
 	segment.fields[Message] = message
@@ -70,3 +76,14 @@ func (segment *Segment) Audit(message string) string {
 		segment.fields,
 	)
 }
+
+// Log logs a message with the given severity for this segment, eg. TRACE or NOTICE
+func (segment *Segment) Log(severity string, message string) string {
+	segment.fields[Message] = message
+
+	return segment.logger.Log(
+		severity,
+		segment.event,
+		segment.fields,
+	)
+}
@@ -0,0 +1,228 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSerializeDepth stops self referencing maps and slices from recursing forever
+const maxSerializeDepth = 32
+
+// serialize returns the fields with every value converted to something json.Marshal writes the way we want to read it.
+// The fields are only copied if a value (or a nested value) had to change.
+func (fields Fields) serialize() Fields {
+	serialized, _ := serializeFields(fields, 0)
+	return serialized
+}
+
+func serializeFields(fields Fields, depth int) (Fields, bool) {
+	var serialized Fields
+	for k, v := range fields {
+		sv, changed := serializeValue(v, depth+1)
+		if !changed {
+			continue
+		}
+
+		if serialized == nil {
+			serialized = make(Fields, len(fields))
+			for k, v := range fields {
+				serialized[k] = v
+			}
+		}
+		serialized[k] = sv
+	}
+
+	if serialized == nil {
+		return fields, false
+	}
+	return serialized, true
+}
+
+// serializeValue returns the value to marshal instead of v, and true if it is different to v
+func serializeValue(v interface{}, depth int) (sv interface{}, changed bool) {
+	// avoid reflection for the common types
+	switch vt := v.(type) {
+	case nil, string, bool, int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8, float64, float32, []string:
+		return v, false
+	case time.Time:
+		return vt.Format(RFC3339Milli), true
+	case *time.Time:
+		if vt == nil {
+			return v, false
+		}
+		return vt.Format(RFC3339Milli), true
+	case time.Duration:
+		return DurationAsISO8601(vt), true
+	case []byte:
+		return serializeBytes(vt), true
+	}
+
+	if depth > maxSerializeDepth {
+		return unserializable(v), true
+	}
+
+	switch vt := v.(type) {
+	case Fields:
+		return serializeFields(vt, depth)
+	case map[string]interface{}:
+		fields, changed := serializeFields(vt, depth)
+		return map[string]interface{}(fields), changed
+	case []interface{}:
+		return serializeList(vt, depth)
+	}
+
+	// values that know how to write themselves, or that can tell us what they mean
+	defer func() {
+		// eg. a nil pointer with a value receiver
+		if r := recover(); r != nil {
+			sv, changed = unserializable(v), true
+		}
+	}()
+	switch vt := v.(type) {
+	case json.Marshaler:
+		return v, false
+	case error:
+		return vt.Error(), true
+	case fmt.Stringer:
+		return vt.String(), true
+	}
+
+	return serializeReflectValue(v, depth)
+}
+
+// serializeList is like a reflected slice, but without reflection and only copied if an item had to change
+func serializeList(list []interface{}, depth int) (interface{}, bool) {
+	var serialized []interface{}
+	for i, item := range list {
+		sv, changed := serializeValue(item, depth+1)
+		if !changed {
+			continue
+		}
+
+		if serialized == nil {
+			serialized = make([]interface{}, len(list))
+			copy(serialized, list)
+		}
+		serialized[i] = sv
+	}
+
+	if serialized == nil {
+		return list, false
+	}
+	return serialized, true
+}
+
+func serializeBytes(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func serializeReflectValue(v interface{}, depth int) (interface{}, bool) {
+	value := reflect.ValueOf(v)
+
+	switch value.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return unserializable(v), true
+
+	case reflect.Pointer:
+		if value.IsNil() {
+			return v, false
+		}
+		return serializeValue(value.Elem().Interface(), depth+1)
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return v, false
+		}
+		if isPlainType(value.Type().Elem()) {
+			// eg. []int or []float64, which json.Marshal writes as they are, so don't walk every item
+			return v, false
+		}
+		items := make([]interface{}, value.Len())
+		changed := false
+		for i := range items {
+			item, itemChanged := serializeValue(value.Index(i).Interface(), depth+1)
+			items[i] = item
+			changed = changed || itemChanged
+		}
+		if !changed {
+			return v, false
+		}
+		return items, true
+
+	case reflect.Map:
+		if value.IsNil() {
+			return v, false
+		}
+		items := make(map[string]interface{}, value.Len())
+		changed := value.Type().Key().Kind() != reflect.String
+		iter := value.MapRange()
+		for iter.Next() {
+			item, itemChanged := serializeValue(iter.Value().Interface(), depth+1)
+			items[fmt.Sprint(iter.Key().Interface())] = item
+			changed = changed || itemChanged
+		}
+		if !changed {
+			return v, false
+		}
+		return items, true
+	}
+
+	// structs etc. are left to json.Marshal
+	return v, false
+}
+
+var (
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// isPlainType returns true for bools, numbers and strings that serializeValue wouldn't change,
+// ie. not a type like time.Duration that is written as something else
+func isPlainType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return !t.Implements(marshalerType) && !t.Implements(errorType) && !t.Implements(stringerType)
+	}
+	return false
+}
+
+// unserializable returns a marker for values that can't be written, so the key isn't silently lost
+func unserializable(v interface{}) string {
+	return fmt.Sprintf("[unserializable %T]", v)
+}
+
+// marshalEachField is the fallback if the fields can't be marshalled as a whole.
+// Each value that can't be marshalled is replaced with a marker, so we still get the rest of the entry.
+func (fields Fields) marshalEachField() ([]byte, error) {
+	marshalled := make(Fields, len(fields))
+	for k, v := range fields {
+		if nested, ok := v.(Fields); ok {
+			bytes, err := nested.marshalEachField()
+			if err != nil {
+				return nil, err
+			}
+			marshalled[k] = json.RawMessage(bytes)
+			continue
+		}
+
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			marshalled[k] = fmt.Sprintf("[unserializable %T: %s]", v, err.Error())
+			continue
+		}
+		marshalled[k] = json.RawMessage(bytes)
+	}
+
+	return json.Marshal(marshalled)
+}
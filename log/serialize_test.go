@@ -0,0 +1,166 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testStringer struct{ name string }
+
+func (s testStringer) String() string { return "stringer " + s.name }
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
+
+type countingStringer struct{ calls *int }
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "counted"
+}
+
+type testMarshaler struct{ fail bool }
+
+func (m testMarshaler) MarshalJSON() ([]byte, error) {
+	if m.fail {
+		return nil, errors.New("marshal failed")
+	}
+	return []byte(`{"custom":true}`), nil
+}
+
+func Test_Serialize_Values(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 678901234, time.UTC)
+	fields := Fields{
+		"error":    errors.New("something failed"),
+		"stringer": testStringer{name: "a"},
+		"ip":       net.IPv4(10, 0, 0, 1),
+		"duration": 1500 * time.Millisecond,
+		"time":     now,
+		"time_ptr": &now,
+		"text":     []byte("hello"),
+		"binary":   []byte{0xff, 0xfe},
+		"func":     func() {},
+		"chan":     make(chan int),
+		"json":     testMarshaler{},
+		"int_map":  map[int]string{1: "one"},
+		"string":   "plain",
+		"number":   42,
+	}
+
+	entry := Fields{}
+	assert.Nil(t, json.Unmarshal([]byte(fields.ToJSON(false)), &entry))
+	assert.Equal(t, "something failed", entry["error"])
+	assert.Equal(t, "stringer a", entry["stringer"])
+	assert.Equal(t, "10.0.0.1", entry["ip"])
+	assert.Equal(t, "P1.5S", entry["duration"])
+	assert.Equal(t, "2026-01-02T03:04:05.678Z", entry["time"])
+	assert.Equal(t, "2026-01-02T03:04:05.678Z", entry["time_ptr"])
+	assert.Equal(t, "hello", entry["text"])
+	assert.Equal(t, "//4=", entry["binary"])
+	assert.Equal(t, "[unserializable func()]", entry["func"])
+	assert.Equal(t, "[unserializable chan int]", entry["chan"])
+	assert.Equal(t, map[string]interface{}{"custom": true}, entry["json"])
+	assert.Equal(t, map[string]interface{}{"1": "one"}, entry["int_map"])
+	assert.Equal(t, "plain", entry["string"])
+	assert.Equal(t, float64(42), entry["number"])
+}
+
+func Test_Serialize_Nested(t *testing.T) {
+	fields := Fields{
+		"nested": Fields{
+			"error": errors.New("nested failed"),
+			"deeper": map[string]interface{}{
+				"duration": time.Second,
+			},
+		},
+		"list":   []interface{}{errors.New("first"), 1, Fields{"func": func() {}}},
+		"errors": []error{errors.New("a"), errors.New("b")},
+		"fields": []Fields{{"duration": time.Minute}},
+	}
+
+	assert.Equal(t, `{"errors":["a","b"],"fields":[{"duration":"P60S"}],"list":["first",1,{"func":"[unserializable func()]"}],"nested":{"deeper":{"duration":"P1S"},"error":"nested failed"}}`, fields.ToJSON(false))
+
+	// the original fields aren't changed
+	assert.IsType(t, time.Second, fields["nested"].(Fields)["deeper"].(map[string]interface{})["duration"])
+}
+
+func Test_Serialize_NoCopy(t *testing.T) {
+	fields := Fields{"string": "a", "number": 1, "nested": Fields{"bool": true}, "list": []string{"a"}}
+
+	serialized := fields.serialize()
+	serialized["added"] = true
+	assert.Contains(t, fields, "added")
+}
+
+func Test_Serialize_Slices(t *testing.T) {
+	ints := make([]int, 10000)
+	value, changed := serializeValue(ints, 0)
+	assert.False(t, changed)
+	assert.Equal(t, ints, value)
+
+	// items that serialize differently are still converted
+	value, changed = serializeValue([]time.Duration{time.Second}, 0)
+	assert.True(t, changed)
+	assert.Equal(t, []interface{}{"P1S"}, value)
+
+	list := []interface{}{"a", 1}
+	value, changed = serializeValue(list, 0)
+	assert.False(t, changed)
+	assert.Equal(t, list, value)
+
+	value, changed = serializeValue([]interface{}{"a", errors.New("failed")}, 0)
+	assert.True(t, changed)
+	assert.Equal(t, []interface{}{"a", "failed"}, value)
+	assert.Equal(t, "a", list[0])
+}
+
+func Test_Serialize_Once(t *testing.T) {
+	calls := 0
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	})
+
+	json := writer.WriteFields(InfoSev, Fields{Event: "counted"}, Fields{"value": countingStringer{calls: &calls}})
+	assert.Contains(t, json, "\"value\":\"counted\"")
+	assert.Equal(t, 1, calls)
+}
+
+func Test_Serialize_NilPointers(t *testing.T) {
+	var err *testError
+	var stringer *testStringer
+
+	assert.Equal(t, `{"error":"[unserializable *log.testError]","pointer":null,"stringer":"[unserializable *log.testStringer]"}`, Fields{
+		"error":    err,
+		"stringer": stringer,
+		"pointer":  (*int)(nil),
+	}.ToJSON(false))
+}
+
+func Test_Serialize_SelfReferencing(t *testing.T) {
+	fields := Fields{}
+	fields["self"] = fields
+
+	json := fields.ToJSON(false)
+	assert.Contains(t, json, "[unserializable log.Fields]")
+}
+
+func Test_Serialize_MarshalFailure(t *testing.T) {
+	fields := Fields{
+		"event":  "marshal_failed",
+		"broken": testMarshaler{fail: true},
+		"nested": Fields{"broken": testMarshaler{fail: true}, "ok": 1},
+	}
+
+	json := fields.ToJSON(false)
+	assert.Contains(t, json, `"event":"marshal_failed"`)
+	assert.Contains(t, json, `"ok":1`)
+	assert.Contains(t, json, `"broken":"[unserializable log.testMarshaler: json: error calling MarshalJSON for type`)
+	assert.Contains(t, json, `marshal failed]"`)
+}
@@ -0,0 +1,185 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+)
+
+// SlogHandler is a slog.Handler that writes records using the same envelope, RequestScopedFields and Writer as Logger.
+// The record message becomes the (snake_case) event, and attributes become properties.
+// An error attribute with the key "err" or "error" is written as the exception.
+type SlogHandler struct {
+	logger Logger
+	fields Fields
+	groups []string
+}
+
+// NewSlogHandler creates a slog.Handler backed by the logger.
+// RequestScopedFields in the context passed to Handle take precedence over those of the logger.
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{
+		logger: *logger,
+		fields: Fields{},
+	}
+}
+
+// NewSlogLogger creates a *slog.Logger backed by the logger.
+func NewSlogLogger(logger *Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(logger))
+}
+
+// Enabled returns true if the level could be enabled on the underlying writer, or the customer or user
+// in the context are being debugged. Event and package level rules are checked by the writer once the record is handled.
+func (handler *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	severity := slogLevelToSeverity(level)
+	if handler.logger.writer.IsEnabled(severity) {
+		return true
+	}
+
+	logger := handler.logger
+	logger.rsFields = handler.requestScopedFields(ctx)
+	return logger.isEnabledFor(logger.newLogScope("", ""), severity)
+}
+
+// Handle writes the record to the underlying writer
+func (handler *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	rsFields := handler.requestScopedFields(ctx)
+
+	var err error
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		if e, ok := attr.Value.Any().(error); ok && err == nil && isSlogErrorKey(attr.Key) {
+			err = e
+			return true
+		}
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	event := snakeCase(record.Message)
+	severity := slogLevelToSeverity(record.Level)
+	properties := handler.logger.fields.Merge(addSlogAttrs(handler.fields, handler.groups, attrs))
+	properties = handler.logger.redactor().Redact(properties)
+
+	sysValues := handler.logger.sysValues
+	loc := unknownLocation
+	if record.PC != 0 {
+		loc = sysValues.getLocationFromPC(record.PC)
+	}
+	system := sysValues.getSystemValues(rsFields, properties, event, severity, loc)
+	if !record.Time.IsZero() {
+		system[Time] = record.Time.UTC().Format(RFC3339Milli)
+	}
+	if err != nil {
+		system = sysValues.getErrorValues(err, system)
+		handler.logger.redactor().redactErrors(system)
+	}
+
+	handler.logger.checkEntry(record.Message, system, properties)
+	handler.logger.writer.WriteFields(severity, system, properties)
+	return nil
+}
+
+// WithAttrs returns a new handler whose attributes consists of both the receiver's attributes and the arguments
+func (handler *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *handler
+	clone.fields = addSlogAttrs(handler.fields, handler.groups, attrs)
+	return &clone
+}
+
+// WithGroup returns a new handler that nests all subsequent attributes in a group with the given name
+func (handler *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return handler
+	}
+
+	clone := *handler
+	clone.groups = append(append([]string{}, handler.groups...), name)
+	return &clone
+}
+
+func (handler *SlogHandler) requestScopedFields(ctx context.Context) gcontext.RequestScopedFields {
+	if ctx != nil {
+		if rsFields, ok := gcontext.GetRequestScopedFields(ctx); ok {
+			return rsFields
+		}
+	}
+
+	return handler.logger.rsFields
+}
+
+// slogLevelToSeverity maps slog levels onto the nearest severity, including TRACE below slog.LevelDebug and NOTICE from slog.LevelInfo+2
+func slogLevelToSeverity(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceSev
+	case level < slog.LevelInfo:
+		return DebugSev
+	case level < slog.LevelInfo+2:
+		return InfoSev
+	case level < slog.LevelWarn:
+		return NoticeSev
+	case level < slog.LevelError:
+		return WarnSev
+	default:
+		return ErrorSev
+	}
+}
+
+func isSlogErrorKey(key string) bool {
+	return key == "err" || key == "error"
+}
+
+// addSlogAttrs returns a copy of fields with the attrs added under the nested groups.
+// Only the maps along the group path are copied, so handlers can safely share fields.
+func addSlogAttrs(fields Fields, groups []string, attrs []slog.Attr) Fields {
+	if len(attrs) == 0 {
+		return fields
+	}
+
+	merged := fields.Merge()
+	if len(groups) > 0 {
+		group, _ := merged[groups[0]].(Fields)
+		merged[groups[0]] = addSlogAttrs(group, groups[1:], attrs)
+		return merged
+	}
+
+	for _, attr := range attrs {
+		addSlogAttr(merged, attr)
+	}
+	return merged
+}
+
+func addSlogAttr(fields Fields, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		fields[attr.Key] = attr.Value.Any()
+		return
+	}
+
+	attrs := attr.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+
+	// groups without a key are inlined
+	if attr.Key == "" {
+		for _, a := range attrs {
+			addSlogAttr(fields, a)
+		}
+		return
+	}
+
+	group, _ := fields[attr.Key].(Fields)
+	group = group.Merge()
+	for _, a := range attrs {
+		addSlogAttr(group, a)
+	}
+	fields[attr.Key] = group
+}
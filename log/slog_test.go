@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSlogTestLogger(level string) (*slog.Logger, *bytes.Buffer) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Level = level
+	})
+	logger := NewWitCustomWriter(rsFields, writer, Fields{"scope": "slog"})
+	return NewSlogLogger(logger), memBuffer
+}
+
+func Test_Slog_Envelope(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(DebugSev)
+
+	logger.Info("User Logged In", "count", 3, slog.String("aString", "hello"))
+
+	json := memBuffer.String()
+	assert.Contains(t, json, "\"event\":\"user_logged_in\"")
+	assert.Contains(t, json, "\"severity\":\"INFO\"")
+	assert.Contains(t, json, "\"trace_id\":\"1-2-3\"")
+	assert.Contains(t, json, "\"customer\":\"hooli\"")
+	assert.Contains(t, json, "\"product\":\"engagement\"")
+	assert.Contains(t, json, "\"app\":\"murmur\"")
+	assert.Contains(t, json, "\"count\":3")
+	assert.Contains(t, json, "\"a_string\":\"hello\"")
+	assert.Contains(t, json, "\"scope\":\"slog\"")
+	assert.Contains(t, json, "slog_test.go")
+}
+
+func Test_Slog_Levels(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(DebugSev)
+
+	logger.Debug("debug_event")
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"DEBUG\"")
+	memBuffer.Reset()
+
+	logger.Warn("warn_event")
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"WARN\"")
+	memBuffer.Reset()
+
+	logger.Error("error_event", "err", errors.New("bad thing"))
+	json := memBuffer.String()
+	assert.Contains(t, json, "\"severity\":\"ERROR\"")
+	assert.Contains(t, json, "\"error\":\"bad thing\"")
+	assert.Contains(t, json, "\"exception\"")
+
+	assert.Equal(t, TraceSev, slogLevelToSeverity(slog.LevelDebug-4))
+	assert.Equal(t, DebugSev, slogLevelToSeverity(slog.LevelDebug))
+	assert.Equal(t, InfoSev, slogLevelToSeverity(slog.LevelInfo+1))
+	assert.Equal(t, NoticeSev, slogLevelToSeverity(slog.LevelInfo+2))
+	assert.Equal(t, WarnSev, slogLevelToSeverity(slog.LevelWarn))
+	assert.Equal(t, ErrorSev, slogLevelToSeverity(slog.LevelError+4))
+}
+
+func Test_Slog_Enabled(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(WarnSev)
+
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelWarn))
+
+	logger.Info("info_event")
+	assert.Empty(t, memBuffer.String())
+}
+
+func Test_Slog_Context(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(DebugSev)
+
+	ctx := gcontext.AddRequestFields(context.Background(), gcontext.RequestScopedFields{
+		TraceID:             "ctx-trace",
+		CustomerAggregateID: "ctx-customer",
+	})
+	logger.InfoContext(ctx, "ctx_event")
+
+	json := memBuffer.String()
+	assert.Contains(t, json, "\"trace_id\":\"ctx-trace\"")
+	assert.Contains(t, json, "\"customer\":\"ctx-customer\"")
+}
+
+func Test_Slog_WithAttrs_WithGroup(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(DebugSev)
+
+	logger = logger.With("job_id", "job-1").WithGroup("survey").With("id", "survey-1")
+	logger.Info("grouped_event", "question", 7, slog.Group("response", "score", 5))
+
+	json := memBuffer.String()
+	assert.Contains(t, json, "\"job_id\":\"job-1\"")
+	assert.Contains(t, json, "\"survey\":{\"id\":\"survey-1\",\"question\":7,\"response\":{\"score\":5}}")
+}
+
+func Test_Slog_EmptyGroup(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(DebugSev)
+
+	logger.WithGroup("empty").Info("no_attrs_event", slog.Group("nothing"))
+
+	json := memBuffer.String()
+	assert.Contains(t, json, "\"event\":\"no_attrs_event\"")
+	assert.NotContains(t, json, "empty")
+	assert.NotContains(t, json, "nothing")
+}
+
+func Test_Slog_Redact(t *testing.T) {
+	logger, memBuffer := newSlogTestLogger(DebugSev)
+
+	logger.Info("login", "password", "hunter2-hunter2")
+	assert.NotContains(t, memBuffer.String(), "hunter2-hunter2")
+}
@@ -18,7 +18,11 @@ import (
 )
 
 const (
-	errorSkipFrames = 4
+	errorSkipFrames  = 4
+	maxErrorCauses   = 32
+	maxCachedCallers = 4096
+	maxCallerDepth   = 32
+	unknownLocation  = "unknown:0:unknown"
 )
 
 // "github.com/pkg/errors" supports this interface for retrieving stack trace on an error
@@ -30,6 +34,18 @@ type stackTracer interface {
 type SystemValues struct {
 }
 
+// callerInfo is the formatted location and package of a calling line
+type callerInfo struct {
+	loc      string
+	pkg      string
+	internal bool
+}
+
+var (
+	callerMutex sync.RWMutex
+	callerCache = map[uintptr]callerInfo{}
+)
+
 // DurationAsISO8601 return a time.Duration as string in ISA8601 format
 func DurationAsISO8601(duration time.Duration) string {
 	return fmt.Sprintf("P%gS", duration.Seconds())
@@ -39,14 +55,14 @@ func newSystemValues() *SystemValues {
 	return &SystemValues{}
 }
 
-func (df SystemValues) getSystemValues(rsFields gcontext.RequestScopedFields, properties Fields, event string, severity string) Fields {
+func (df SystemValues) getSystemValues(rsFields gcontext.RequestScopedFields, properties Fields, event string, severity string, loc string) Fields {
 	fields := Fields{
 		Time:     df.timeNow(RFC3339Milli),
 		Event:    event,
 		Resource: df.hostName(),
 		Os:       df.targetOS(),
 		Severity: severity,
-		Loc:      df.getLocation(4),
+		Loc:      loc,
 	}
 	fields = df.getMandatoryFields(rsFields, fields, properties)
 	fields = df.getEnvFields(fields, properties)
@@ -62,8 +78,11 @@ func (df SystemValues) getErrorValues(err error, fields Fields) Fields {
 	debug.ReadGCStats(stats)
 
 	fields[Exception] = Fields{
-		"error": errorMessage,
-		"trace": stack,
+		"error":  errorMessage,
+		"type":   fmt.Sprintf("%T", err),
+		"trace":  stack,
+		"frames": df.getErrorFrames(err),
+		"causes": df.getErrorCauses(err),
 		"gc_stats": Fields{
 			"last_gc":        stats.LastGC,
 			"num_gc":         stats.NumGC,
@@ -78,6 +97,12 @@ func (df SystemValues) getErrorValues(err error, fields Fields) Fields {
 }
 
 func (df SystemValues) getErrorStackTrace(err error) string {
+	// did it capture the stack where it happened, eg. a recovered panic?
+	var ste stackError
+	if errors.As(err, &ste) {
+		return ste.stackTrace()
+	}
+
 	// is it the standard google error type?
 	var se *gerrors.Error
 	if errors.As(err, &se) {
@@ -94,6 +119,133 @@ func (df SystemValues) getErrorStackTrace(err error) string {
 	return df.getCurrentStack(errorSkipFrames)
 }
 
+// getErrorFrames returns the same stack as getErrorStackTrace, but as a list of file, line and function
+func (df SystemValues) getErrorFrames(err error) []Fields {
+	var ste stackError
+	if errors.As(err, &ste) {
+		return ste.stackFrames()
+	}
+
+	var se *gerrors.Error
+	if errors.As(err, &se) {
+		return df.getGoErrorFrames(se)
+	}
+
+	var ews stackTracer
+	if errors.As(err, &ews) {
+		return df.getStackTracerFrames(ews)
+	}
+
+	// skip 4 frames that belong to glamplify
+	return df.getCurrentFrames(errorSkipFrames)
+}
+
+// getErrorCauses walks the tree of wrapped and joined errors below err, depth first
+func (df SystemValues) getErrorCauses(err error) []Fields {
+	causes := []Fields{}
+
+	var walk func(err error)
+	walk = func(err error) {
+		for _, cause := range unwrapErrors(err) {
+			if len(causes) >= maxErrorCauses {
+				return
+			}
+			causes = append(causes, df.getErrorCause(cause))
+			walk(cause)
+		}
+	}
+	walk(err)
+
+	return causes
+}
+
+func (df SystemValues) getErrorCause(err error) Fields {
+	cause := Fields{
+		"error": strings.TrimSpace(err.Error()),
+		"type":  fmt.Sprintf("%T", err),
+	}
+
+	// only include a stack if this error captured one itself, otherwise it is the same as its parent
+	switch e := err.(type) {
+	case stackError:
+		cause["frames"] = e.stackFrames()
+	case *gerrors.Error:
+		cause["frames"] = df.getGoErrorFrames(e)
+	case stackTracer:
+		cause["frames"] = df.getStackTracerFrames(e)
+	}
+
+	return cause
+}
+
+func unwrapErrors(err error) []error {
+	var unwrapped []error
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		unwrapped = e.Unwrap()
+	case interface{ Unwrap() error }:
+		unwrapped = []error{e.Unwrap()}
+	}
+
+	causes := make([]error, 0, len(unwrapped))
+	for _, cause := range unwrapped {
+		if cause != nil {
+			causes = append(causes, cause)
+		}
+	}
+	return causes
+}
+
+func (df SystemValues) getGoErrorFrames(se *gerrors.Error) []Fields {
+	stackFrames := se.StackFrames()
+
+	frames := make([]Fields, 0, len(stackFrames))
+	for _, f := range stackFrames {
+		frames = append(frames, newFrameFields(f.File, f.LineNumber, f.Package+"."+f.Name))
+	}
+	return frames
+}
+
+func (df SystemValues) getStackTracerFrames(ews stackTracer) []Fields {
+	stackTrace := ews.StackTrace()
+
+	frames := make([]Fields, 0, len(stackTrace))
+	for _, f := range stackTrace {
+		// a pkg/errors Frame is the program counter + 1
+		pc := uintptr(f) - 1
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			frames = append(frames, newFrameFields(Unknown, 0, Unknown))
+			continue
+		}
+		file, line := fn.FileLine(pc)
+		frames = append(frames, newFrameFields(file, line, fn.Name()))
+	}
+	return frames
+}
+
+func (df SystemValues) getCurrentFrames(skip int) []Fields {
+	stack := make([]uintptr, gerrors.MaxStackDepth)
+	length := runtime.Callers(skip, stack[:])
+
+	frames := make([]Fields, 0, length)
+	callers := runtime.CallersFrames(stack[:length])
+	for more := length > 0; more; {
+		var frame runtime.Frame
+		frame, more = callers.Next()
+		frames = append(frames, newFrameFields(frame.File, frame.Line, frame.Function))
+	}
+	return frames
+}
+
+func newFrameFields(file string, line int, function string) Fields {
+	return Fields{
+		"file":     file,
+		"line":     line,
+		"function": function,
+	}
+}
+
 func (df SystemValues) getStackTracer(ews stackTracer) string {
 	frames := ews.StackTrace()
 
@@ -182,18 +334,69 @@ func (df SystemValues) timeNow(format string) string {
 }
 
 func (df SystemValues) getLocation(caller int) string {
-	pc, file, line, ok := runtime.Caller(caller)
-	for ok && strings.Contains(file, "glamplify"){
-		caller++
-		pc, file, line, ok = runtime.Caller(caller)
+	return df.getCaller(caller).loc
+}
+
+// getCaller returns the location and package of the first caller, skip frames up, that isn't part of glamplify.
+// Results are cached per program counter, so only the first call from each line pays for looking up and formatting.
+func (df SystemValues) getCaller(skip int) callerInfo {
+	var pcs [maxCallerDepth]uintptr
+	// +2 for runtime.Callers and this func
+	length := runtime.Callers(skip+2, pcs[:])
+
+	for _, pc := range pcs[:length] {
+		info, found := cachedCaller(pc)
+		if !found {
+			info = cacheCaller(pc, newCallerInfo(pc))
+		}
+		if !info.internal {
+			return info
+		}
+	}
+
+	return callerInfo{loc: unknownLocation}
+}
+
+func (df SystemValues) getLocationFromPC(pc uintptr) string {
+	info, found := cachedCaller(pc)
+	if !found {
+		info = cacheCaller(pc, newCallerInfo(pc))
+	}
+
+	return info.loc
+}
+
+func newCallerInfo(pc uintptr) callerInfo {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	frame, _ := frames.Next()
+	if frame.Function == "" {
+		return callerInfo{loc: unknownLocation}
 	}
-	if !ok {
-		return "unknown:0:unknown"
+
+	return callerInfo{
+		loc:      fmt.Sprintf("%s:%d:%s", frame.File, frame.Line, frame.Function),
+		pkg:      packageFromFunction(frame.Function),
+		internal: strings.Contains(frame.File, "glamplify"),
 	}
+}
+
+func cachedCaller(pc uintptr) (callerInfo, bool) {
+	callerMutex.RLock()
+	defer callerMutex.RUnlock()
 
-	fn := runtime.FuncForPC(pc)
-	methodName := fn.Name()
-	return fmt.Sprintf("%s:%d:%s", file, line, methodName)
+	info, found := callerCache[pc]
+	return info, found
+}
+
+func cacheCaller(pc uintptr, info callerInfo) callerInfo {
+	callerMutex.Lock()
+	defer callerMutex.Unlock()
+
+	// the number of calling lines is fixed, but don't grow forever if something generates code at runtime
+	if len(callerCache) < maxCachedCallers {
+		callerCache[pc] = info
+	}
+	return info
 }
 
 var host string
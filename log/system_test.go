@@ -23,7 +23,7 @@ func Test_HostName(t *testing.T) {
 func Test_Default(t *testing.T) {
 	df := newSystemValues()
 
-	fields := df.getSystemValues(rsFields, nil, "event_name", DebugSev)
+	fields := df.getSystemValues(rsFields, nil, "event_name", DebugSev, df.getLocation(1))
 
 	_, ok := fields[Time]
 	assert.True(t, ok)
@@ -58,7 +58,7 @@ func Test_Default(t *testing.T) {
 func Test_ErrorDefault(t *testing.T) {
 	df := newSystemValues()
 
-	fields := df.getSystemValues(rsFields, nil, "event_name", DebugSev)
+	fields := df.getSystemValues(rsFields, nil, "event_name", DebugSev, df.getLocation(1))
 	fields = df.getErrorValues(errors.New("test err"), fields)
 
 	_, ok := fields[Exception]
@@ -108,3 +108,52 @@ func Test_CurrentStack(t *testing.T) {
 	fmt.Println(stack1)
 }
 
+func Test_ErrorFrames(t *testing.T) {
+	df := newSystemValues()
+
+	assert.NotEmpty(t, df.getErrorFrames(errors.New("system error")))
+
+	for _, err := range []error{gerrors.New("g error"), perrors.New("p error")} {
+		frames := df.getErrorFrames(err)
+		assert.NotEmpty(t, frames, "%T", err)
+		assert.Contains(t, frames[0]["file"], "system_test.go", "%T", err)
+		assert.Greater(t, frames[0]["line"], 0, "%T", err)
+		assert.Contains(t, frames[0]["function"], "Test_ErrorFrames", "%T", err)
+	}
+}
+
+func Test_ErrorCauses(t *testing.T) {
+	df := newSystemValues()
+
+	root := perrors.New("connection refused")
+	joined := errors.Join(fmt.Errorf("query failed: %w", root), errors.New("rollback failed"))
+	err := fmt.Errorf("save survey: %w", joined)
+
+	causes := df.getErrorCauses(err)
+	assert.Len(t, causes, 4)
+	assert.Equal(t, "query failed: connection refused\nrollback failed", causes[0]["error"])
+	assert.Equal(t, "*fmt.wrapError", causes[1]["type"])
+	assert.Equal(t, "query failed: connection refused", causes[1]["error"])
+	assert.Equal(t, "connection refused", causes[2]["error"])
+	assert.NotEmpty(t, causes[2]["frames"])
+	assert.Equal(t, "rollback failed", causes[3]["error"])
+	assert.Nil(t, causes[3]["frames"])
+
+	assert.Empty(t, df.getErrorCauses(errors.New("plain")))
+}
+
+func Test_ErrorValues_Causes(t *testing.T) {
+	df := newSystemValues()
+
+	fields := df.getErrorValues(fmt.Errorf("outer: %w", gerrors.New("inner")), Fields{})
+	exception := fields[Exception].(Fields)
+	assert.Equal(t, "outer: inner", exception["error"])
+	assert.Equal(t, "*fmt.wrapError", exception["type"])
+	assert.NotEmpty(t, exception["trace"])
+	assert.NotEmpty(t, exception["frames"])
+
+	causes := exception["causes"].([]Fields)
+	assert.Equal(t, "*errors.Error", causes[0]["type"])
+	assert.NotEmpty(t, causes[0]["frames"])
+	assert.Contains(t, fields.ToJSON(false), "\"causes\":[{")
+}
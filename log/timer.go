@@ -0,0 +1,99 @@
+package log
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timer measures an operation started with Segment.Start. When it is stopped it adds time_taken, time_taken_ms,
+// memory_used (the change in heap size, which can be negative if a GC ran) and any item counts to the entry.
+// Reading the heap size briefly stops the world, so don't time very hot loops.
+type Timer struct {
+	segment   *Segment
+	start     time.Time
+	startHeap uint64
+
+	items     int64
+	requested int64
+
+	once   sync.Once
+	fields Fields
+}
+
+// Start starts a Timer for this segment
+// eg. defer logger.Event("import_users").Start().Done(&err, "imported users")
+func (segment *Segment) Start() *Timer {
+	return &Timer{
+		segment:   segment,
+		start:     time.Now(),
+		startHeap: heapAlloc(),
+	}
+}
+
+// Items adds n to the number of items processed. Safe to call from multiple go routines.
+func (timer *Timer) Items(n int) *Timer {
+	atomic.AddInt64(&timer.items, int64(n))
+	return timer
+}
+
+// TotalItems sets the total number of items requested
+func (timer *Timer) TotalItems(n int) *Timer {
+	atomic.StoreInt64(&timer.requested, int64(n))
+	return timer
+}
+
+// Stop stops the timer (if it hasn't already been stopped) and returns the measured fields
+func (timer *Timer) Stop() Fields {
+	timer.once.Do(func() {
+		duration := time.Since(timer.start)
+
+		timer.fields = NewDurationFields(duration)
+		timer.fields[MemoryUsed] = int64(heapAlloc()) - int64(timer.startHeap)
+		if items := atomic.LoadInt64(&timer.items); items > 0 {
+			timer.fields[ItemsProcessed] = items
+		}
+		if requested := atomic.LoadInt64(&timer.requested); requested > 0 {
+			timer.fields[TotalItemsRequested] = requested
+		}
+	})
+
+	return timer.fields
+}
+
+// Debug stops the timer and logs a debug message with the measured fields
+func (timer *Timer) Debug(message string) string {
+	return timer.stopped().Debug(message)
+}
+
+// Info stops the timer and logs an info message with the measured fields
+func (timer *Timer) Info(message string) string {
+	return timer.stopped().Info(message)
+}
+
+// Error stops the timer and logs an error with the measured fields
+func (timer *Timer) Error(err error) string {
+	return timer.stopped().Error(err)
+}
+
+// Done stops the timer and logs an error if *errp is not nil, otherwise an info message. Use with defer and a named error
+// eg. func importUsers() (err error) { defer logger.Event("import_users").Start().Done(&err, "imported users"); ... }
+func (timer *Timer) Done(errp *error, message string) string {
+	if errp != nil && *errp != nil {
+		return timer.Error(*errp)
+	}
+	return timer.Info(message)
+}
+
+// stopped returns a new segment with the segment's fields and the measured fields, so the original segment is unchanged
+func (timer *Timer) stopped() *Segment {
+	measured := timer.Stop()
+	return timer.segment.logger.Event(timer.segment.event).Fields(timer.segment.fields, measured)
+}
+
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
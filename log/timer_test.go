@@ -0,0 +1,85 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Timer_Info(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+
+	timer := logger.Event("import_users").Fields(Fields{"source": "csv"}).Start()
+	timer.TotalItems(10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timer.Items(2)
+		}()
+	}
+	wg.Wait()
+	time.Sleep(2 * time.Millisecond)
+
+	json := timer.Info("imported users")
+	assert.Contains(t, json, "\"event\":\"import_users\"")
+	assert.Contains(t, json, "\"message\":\"imported users\"")
+	assert.Contains(t, json, "\"source\":\"csv\"")
+	assert.Contains(t, json, "\"items_processed\":8")
+	assert.Contains(t, json, "\"total_items_requested\":10")
+	assert.Contains(t, json, "\"memory_used\":")
+	assert.Contains(t, json, "\"time_taken\":\"P0.")
+	assert.Contains(t, json, "\"time_taken_ms\":")
+}
+
+func Test_Timer_Stop(t *testing.T) {
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}))
+
+	timer := logger.Event("work").Start()
+	time.Sleep(2 * time.Millisecond)
+	fields := timer.Stop()
+
+	assert.GreaterOrEqual(t, fields[TimeTakenMS], int64(2))
+	assert.Contains(t, fields, MemoryUsed)
+	assert.NotContains(t, fields, ItemsProcessed)
+
+	// stopping again returns the same measurement
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, fields, timer.Stop())
+}
+
+func Test_Timer_Done(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	logger := NewWitCustomWriter(rsFields, NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}))
+
+	work := func(fail bool) (err error) {
+		defer logger.Event("work").Start().Done(&err, "worked")
+		if fail {
+			return errors.New("failed to work")
+		}
+		return nil
+	}
+
+	assert.Nil(t, work(false))
+	assert.Contains(t, memBuffer.String(), "\"message\":\"worked\"")
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"INFO\"")
+
+	memBuffer.Reset()
+	assert.NotNil(t, work(true))
+	assert.Contains(t, memBuffer.String(), "\"error\":\"failed to work\"")
+	assert.Contains(t, memBuffer.String(), "\"severity\":\"ERROR\"")
+	assert.Contains(t, memBuffer.String(), "\"time_taken_ms\":")
+}
@@ -0,0 +1,259 @@
+package log
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxErrorTraces     = 10000
+	defaultMaxBufferedTraces  = 100
+	defaultMaxBufferedEntries = 50
+	defaultMaxBufferedAge     = 10 * time.Second
+	traceHashBuckets          = 1000000
+)
+
+// TraceSamplingWriterConfig for setting initial values for TraceSamplingWriter
+type TraceSamplingWriterConfig struct {
+	// KeepPercent of traces are written, from 0 to 100 (default)
+	KeepPercent float64
+	// KeepErrors writes the whole of a trace that logs an ERROR or FATAL, even if it wasn't sampled (default true).
+	// The entries of unsampled traces are held until the trace logs an error, then written before it.
+	KeepErrors bool
+	// MaxErrorTraces is how many traces with errors are remembered for KeepErrors, the oldest are forgotten first
+	MaxErrorTraces int
+	// MaxBufferedTraces is how many unsampled traces have their entries held for KeepErrors, the least recent are forgotten first. Default 100.
+	MaxBufferedTraces int
+	// MaxBufferedEntries is how many entries are held per unsampled trace, the oldest are dropped first. Default 50.
+	MaxBufferedEntries int
+	// MaxBufferedAge is how long a trace's entries are held after its last entry, so finished requests don't stay in memory. Default 10 seconds.
+	MaxBufferedAge time.Duration
+}
+
+// heldTrace is the entries of an unsampled trace, held in case it logs an error
+type heldTrace struct {
+	entries  []tracedEntry
+	lastHeld time.Time
+}
+
+type tracedEntry struct {
+	sev        string
+	system     Fields
+	properties Fields
+}
+
+// TraceSamplingWriter sits in front of another Writer and keeps or drops whole requests.
+// The decision is a hash of the TraceID (or CorrelationID if there is no TraceID), so every writer and service
+// makes the same decision for a request. Entries without either are always written.
+type TraceSamplingWriter struct {
+	nextWriter
+	keepBelow  uint32
+	sampleRate float64
+	keepErrors bool
+
+	mutex              sync.Mutex
+	errorTraces        map[string]struct{}
+	errorOrder         []string
+	maxErrorTraces     int
+	buffered           map[string]*heldTrace
+	bufferedOrder      []string
+	maxBufferedTraces  int
+	maxBufferedEntries int
+	maxBufferedAge     time.Duration
+
+	now func() time.Time
+}
+
+// NewTraceSamplingWriter creates a new TraceSamplingWriter in front of next
+func NewTraceSamplingWriter(next Writer, configure ...func(*TraceSamplingWriterConfig)) *TraceSamplingWriter {
+	conf := TraceSamplingWriterConfig{
+		KeepPercent:        100,
+		KeepErrors:         true,
+		MaxErrorTraces:     defaultMaxErrorTraces,
+		MaxBufferedTraces:  defaultMaxBufferedTraces,
+		MaxBufferedEntries: defaultMaxBufferedEntries,
+		MaxBufferedAge:     defaultMaxBufferedAge,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if conf.KeepPercent < 0 {
+		conf.KeepPercent = 0
+	}
+	if conf.KeepPercent > 100 {
+		conf.KeepPercent = 100
+	}
+	if conf.MaxErrorTraces <= 0 {
+		conf.MaxErrorTraces = defaultMaxErrorTraces
+	}
+	if conf.MaxBufferedTraces <= 0 {
+		conf.MaxBufferedTraces = defaultMaxBufferedTraces
+	}
+	if conf.MaxBufferedEntries <= 0 {
+		conf.MaxBufferedEntries = defaultMaxBufferedEntries
+	}
+	if conf.MaxBufferedAge <= 0 {
+		conf.MaxBufferedAge = defaultMaxBufferedAge
+	}
+
+	writer := &TraceSamplingWriter{
+		nextWriter:         nextWriter{next: next},
+		keepBelow:          uint32(conf.KeepPercent / 100 * traceHashBuckets),
+		keepErrors:         conf.KeepErrors,
+		errorTraces:        map[string]struct{}{},
+		maxErrorTraces:     conf.MaxErrorTraces,
+		buffered:           map[string]*heldTrace{},
+		maxBufferedTraces:  conf.MaxBufferedTraces,
+		maxBufferedEntries: conf.MaxBufferedEntries,
+		maxBufferedAge:     conf.MaxBufferedAge,
+		now:                time.Now,
+	}
+	if conf.KeepPercent > 0 {
+		writer.sampleRate = 100 / conf.KeepPercent
+	}
+
+	return writer
+}
+
+// WriteFields writes the entry to the next writer if its trace is kept, and returns "" if it is not.
+// With KeepErrors the entries of an unsampled trace are held, and written when the trace logs an ERROR or FATAL.
+func (writer *TraceSamplingWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	id := traceIDFrom(system)
+	if id == "" {
+		return writer.next.WriteFields(sev, system, fields...)
+	}
+
+	if writer.isSampled(id) {
+		if writer.sampleRate > 1 {
+			system[SampleRate] = writer.sampleRate
+		}
+		return writer.next.WriteFields(sev, system, fields...)
+	}
+	if !writer.keepErrors {
+		return ""
+	}
+
+	held, keep := writer.holdUntilError(id, sev, system, fields...)
+	if !keep {
+		return ""
+	}
+
+	// kept because of an error, so each entry represents just itself
+	for _, entry := range held {
+		entry.system[SampleRate] = 1
+		writer.next.WriteFields(entry.sev, entry.system, entry.properties)
+	}
+	system[SampleRate] = 1
+	return writer.next.WriteFields(sev, system, fields...)
+}
+
+func (writer *TraceSamplingWriter) isSampled(id string) bool {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	return hash.Sum32()%traceHashBuckets < writer.keepBelow
+}
+
+// holdUntilError returns true if the entry of an unsampled trace should be written because the trace has logged an error,
+// with the entries held before the error. Otherwise the entry is held and false is returned.
+func (writer *TraceSamplingWriter) holdUntilError(id string, sev string, system Fields, fields ...Fields) ([]tracedEntry, bool) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if _, found := writer.errorTraces[id]; found {
+		return nil, true
+	}
+
+	if sev == ErrorSev || sev == FatalSev {
+		writer.rememberError(id)
+		return writer.takeBuffered(id), true
+	}
+
+	writer.buffer(id, tracedEntry{
+		sev: sev,
+		// copy as the caller may change the fields after logging
+		system:     system.clone(),
+		properties: Fields{}.Merge(fields...).clone(),
+	})
+	return nil, false
+}
+
+// buffer must be called with the mutex held. bufferedOrder is least recently held first, so traces that stopped
+// logging (eg. finished requests) are forgotten once they are older than maxBufferedAge, or to make room.
+func (writer *TraceSamplingWriter) buffer(id string, entry tracedEntry) {
+	now := writer.now()
+	writer.forgetBuffered(now)
+
+	trace, found := writer.buffered[id]
+	if found {
+		writer.removeBufferedOrder(id)
+	} else {
+		if len(writer.bufferedOrder) >= writer.maxBufferedTraces {
+			delete(writer.buffered, writer.bufferedOrder[0])
+			writer.bufferedOrder = writer.bufferedOrder[1:]
+		}
+		trace = &heldTrace{}
+		writer.buffered[id] = trace
+	}
+	writer.bufferedOrder = append(writer.bufferedOrder, id)
+
+	if len(trace.entries) >= writer.maxBufferedEntries {
+		trace.entries = trace.entries[1:]
+	}
+	trace.entries = append(trace.entries, entry)
+	trace.lastHeld = now
+}
+
+// forgetBuffered must be called with the mutex held
+func (writer *TraceSamplingWriter) forgetBuffered(now time.Time) {
+	for len(writer.bufferedOrder) > 0 {
+		oldest := writer.bufferedOrder[0]
+		if now.Sub(writer.buffered[oldest].lastHeld) < writer.maxBufferedAge {
+			return
+		}
+		delete(writer.buffered, oldest)
+		writer.bufferedOrder = writer.bufferedOrder[1:]
+	}
+}
+
+func (writer *TraceSamplingWriter) takeBuffered(id string) []tracedEntry {
+	writer.forgetBuffered(writer.now())
+
+	trace, found := writer.buffered[id]
+	if !found {
+		return nil
+	}
+
+	delete(writer.buffered, id)
+	writer.removeBufferedOrder(id)
+	return trace.entries
+}
+
+func (writer *TraceSamplingWriter) removeBufferedOrder(id string) {
+	for i, buffered := range writer.bufferedOrder {
+		if buffered == id {
+			writer.bufferedOrder = append(writer.bufferedOrder[:i], writer.bufferedOrder[i+1:]...)
+			return
+		}
+	}
+}
+
+// rememberError must be called with the mutex held
+func (writer *TraceSamplingWriter) rememberError(id string) {
+	if len(writer.errorOrder) >= writer.maxErrorTraces {
+		oldest := writer.errorOrder[0]
+		writer.errorOrder = writer.errorOrder[1:]
+		delete(writer.errorTraces, oldest)
+	}
+	writer.errorTraces[id] = struct{}{}
+	writer.errorOrder = append(writer.errorOrder, id)
+}
+
+func traceIDFrom(system Fields) string {
+	if id, _ := system[TraceID].(string); id != "" {
+		return id
+	}
+
+	id, _ := system[CorrelationID].(string)
+	return id
+}
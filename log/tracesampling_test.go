@@ -0,0 +1,185 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TraceSamplingWriter_Consistent(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 25
+	})
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		logger := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: fmt.Sprintf("1-%d", i)}, writer)
+
+		first := logger.Info("first_event") != ""
+		second := logger.Debug("second_event") != ""
+		assert.Equal(t, first, second, "all or nothing for a trace")
+		if first {
+			kept++
+		}
+	}
+
+	assert.InDelta(t, 250, kept, 60)
+	assert.Contains(t, memBuffer.String(), "\"sample_rate\":4,")
+}
+
+func Test_TraceSamplingWriter_Fallbacks(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+	})
+
+	noIDs := NewWitCustomWriter(gcontext.RequestScopedFields{}, writer)
+	assert.NotEmpty(t, noIDs.Info("no_ids"))
+
+	correlated := NewWitCustomWriter(gcontext.RequestScopedFields{CorrelationID: "abc"}, writer)
+	assert.Empty(t, correlated.Info("correlated"))
+	assert.NotContains(t, memBuffer.String(), SampleRate)
+}
+
+func Test_TraceSamplingWriter_KeepErrors(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+		conf.MaxErrorTraces = 2
+	})
+
+	logger := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "1-2-3"}, writer)
+	assert.Empty(t, logger.Info("before_error", Fields{"step": 1}))
+	assert.Empty(t, memBuffer.String())
+	assert.NotEmpty(t, logger.Error("failed", errors.New("failed")))
+	assert.NotEmpty(t, logger.Info("after_error"))
+
+	// the entries held before the error are written first
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "\"event\":\"before_error\"")
+	assert.Contains(t, lines[0], "\"step\":1")
+	assert.Contains(t, lines[1], "\"event\":\"failed\"")
+	assert.Contains(t, lines[2], "\"event\":\"after_error\"")
+	assert.Equal(t, 3, strings.Count(memBuffer.String(), "\"sample_rate\":1,"))
+
+	// the oldest error traces are forgotten
+	for _, id := range []string{"a", "b"} {
+		NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: id}, writer).Error("failed", errors.New("failed"))
+	}
+	assert.Empty(t, logger.Info("forgotten"))
+}
+
+func Test_TraceSamplingWriter_KeepErrors_Limits(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+		conf.MaxBufferedTraces = 1
+		conf.MaxBufferedEntries = 2
+	})
+
+	first := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "1"}, writer)
+	second := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "2"}, writer)
+
+	for i := 0; i < 3; i++ {
+		first.Info("first_event", Fields{"step": i})
+	}
+	first.Error("failed", errors.New("failed"))
+	// only the last 2 entries are held
+	assert.NotContains(t, memBuffer.String(), "\"step\":0")
+	assert.Contains(t, memBuffer.String(), "\"step\":1")
+	assert.Contains(t, memBuffer.String(), "\"step\":2")
+
+	// the oldest trace is forgotten when another starts
+	memBuffer.Reset()
+	third := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "3"}, writer)
+	second.Info("second_event")
+	third.Info("third_event")
+	second.Error("failed", errors.New("failed"))
+	third.Error("failed", errors.New("failed"))
+	assert.NotContains(t, memBuffer.String(), "second_event")
+	assert.Contains(t, memBuffer.String(), "third_event")
+}
+
+func Test_TraceSamplingWriter_KeepErrors_Age(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+		conf.MaxBufferedTraces = 2
+		conf.MaxBufferedAge = time.Minute
+	})
+	now := time.Now()
+	writer.now = func() time.Time { return now }
+
+	finished := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "1"}, writer)
+	running := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "2"}, writer)
+	finished.Info("finished_event")
+	running.Info("running_start")
+
+	// a trace that is still logging is kept, one that stopped is forgotten once it is too old
+	now = now.Add(50 * time.Second)
+	running.Info("running_step")
+	now = now.Add(50 * time.Second)
+
+	finished.Error("failed", errors.New("failed"))
+	running.Error("failed", errors.New("failed"))
+	assert.NotContains(t, memBuffer.String(), "finished_event")
+	assert.Contains(t, memBuffer.String(), "running_start")
+	assert.Contains(t, memBuffer.String(), "running_step")
+
+	// the least recently held trace makes room for a new one
+	memBuffer.Reset()
+	for _, id := range []string{"3", "4", "3", "5"} {
+		NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: id}, writer).Info("held_" + id)
+	}
+	assert.Equal(t, []string{"3", "5"}, writer.bufferedOrder)
+}
+
+func Test_TraceSamplingWriter_KeepErrors_Copies(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+	})
+
+	system := Fields{TraceID: "1-2-3", Event: "held"}
+	properties := Fields{"step": 1}
+	writer.WriteFields(InfoSev, system, properties)
+	properties["step"] = 2
+	system[Event] = "changed"
+
+	writer.WriteFields(ErrorSev, Fields{TraceID: "1-2-3", Event: "failed"})
+	assert.Contains(t, memBuffer.String(), "\"event\":\"held\"")
+	assert.Contains(t, memBuffer.String(), "\"step\":1")
+}
+
+func Test_TraceSamplingWriter_KeepErrors_Off(t *testing.T) {
+	writer := NewTraceSamplingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}), func(conf *TraceSamplingWriterConfig) {
+		conf.KeepPercent = 0
+		conf.KeepErrors = false
+	})
+
+	logger := NewWitCustomWriter(gcontext.RequestScopedFields{TraceID: "1-2-3"}, writer)
+	assert.Empty(t, logger.Error("failed", errors.New("failed")))
+	assert.Empty(t, logger.Info("after_error"))
+}
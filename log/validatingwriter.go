@@ -0,0 +1,109 @@
+package log
+
+import (
+	systemLog "log"
+	"strings"
+	"sync"
+
+	"github.com/cultureamp/glamplify/env"
+)
+
+// ValidatingWriterConfig for setting initial values for ValidatingWriter
+type ValidatingWriterConfig struct {
+	// Registry of event schemas. Default is an empty registry, so only the snake_case conventions are checked.
+	Registry *SchemaRegistry
+	// Warn prints each violation to stderr. Default true if APP_ENV is development, dev or local, otherwise violations are only counted.
+	Warn bool
+}
+
+// ValidatingWriter sits in front of another Writer and checks the entries logged with a Logger or SlogHandler against a
+// SchemaRegistry, using the event and property names as they were logged, before they are snake_cased.
+// Entries are always written, violations are printed (in development) and counted (see Violations).
+type ValidatingWriter struct {
+	nextWriter
+	registry *SchemaRegistry
+	warn     bool
+
+	mutex      sync.Mutex
+	violations map[string]uint64
+}
+
+// entryChecker is implemented by writers that check entries as they were logged, with the event name before it
+// is snake_cased (eg. ValidatingWriter). The Logger calls it before writing or buffering the entry.
+type entryChecker interface {
+	checkEntry(event string, system Fields, properties Fields)
+}
+
+// NewValidatingWriter creates a new ValidatingWriter in front of next
+func NewValidatingWriter(next Writer, configure ...func(*ValidatingWriterConfig)) *ValidatingWriter {
+	conf := ValidatingWriterConfig{
+		Warn: isDevelopment(env.GetString(env.AppEnv, "")),
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+	if conf.Registry == nil {
+		conf.Registry = NewSchemaRegistry()
+	}
+
+	return &ValidatingWriter{
+		nextWriter: nextWriter{next: next},
+		registry:   conf.Registry,
+		warn:       conf.Warn,
+		violations: map[string]uint64{},
+	}
+}
+
+// WriteFields writes the entry to the next writer. It was checked when it was logged, see checkEntry.
+func (writer *ValidatingWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
+	return writer.next.WriteFields(sev, system, fields...)
+}
+
+// Registry returns the SchemaRegistry entries are checked against
+func (writer *ValidatingWriter) Registry() *SchemaRegistry {
+	return writer.registry
+}
+
+// Violations returns how many entries for each event didn't match the conventions or their schema
+func (writer *ValidatingWriter) Violations() map[string]uint64 {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	violations := make(map[string]uint64, len(writer.violations))
+	for event, count := range writer.violations {
+		violations[event] = count
+	}
+	return violations
+}
+
+func (writer *ValidatingWriter) checkEntry(event string, system Fields, properties Fields) {
+	writer.validate(event, system, properties)
+	writer.nextWriter.checkEntry(event, system, properties)
+}
+
+func (writer *ValidatingWriter) validate(event string, system Fields, properties Fields) {
+	violations := writer.registry.Validate(event, properties)
+	if len(violations) == 0 {
+		return
+	}
+
+	// counted by the event name that is written
+	written, _ := system[Event].(string)
+	writer.mutex.Lock()
+	writer.violations[written]++
+	writer.mutex.Unlock()
+
+	if writer.warn {
+		for _, violation := range violations {
+			systemLog.Printf("log schema violation: %s", violation.String())
+		}
+	}
+}
+
+func isDevelopment(appEnv string) bool {
+	switch strings.ToLower(appEnv) {
+	case "development", "dev", "local":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	systemLog "log"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidatingWriter(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewValidatingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *ValidatingWriterConfig) {
+		conf.Registry = NewSchemaRegistry(surveySavedSchema)
+		conf.Warn = false
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("survey_saved", Fields{"survey_id": "123"})
+	assert.Empty(t, writer.Violations())
+
+	// entries are still written
+	assert.NotEmpty(t, logger.Info("survey_saved", Fields{"title": 123}))
+	logger.Info("survey_saved")
+	logger.Info("survey.opened")
+	assert.Equal(t, map[string]uint64{"survey_saved": 2, "survey.opened": 1}, writer.Violations())
+	assert.Contains(t, memBuffer.String(), "\"title\":123")
+}
+
+func Test_ValidatingWriter_LoggedNames(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewValidatingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	}), func(conf *ValidatingWriterConfig) {
+		conf.Warn = false
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	// the names are checked as they were logged, not as they are written
+	logger.Info("UserLogin")
+	logger.Info("user login")
+	logger.Info("userLOGIN")
+	logger.Info("user_login", Fields{"BadKey": 1})
+	logger.Info("user_logout", Fields{"good_key": 1})
+	assert.Equal(t, uint64(4), sumViolations(writer.Violations()))
+	assert.NotContains(t, writer.Violations(), "user_logout")
+
+	// and by the slog handler
+	slog.New(NewSlogHandler(logger)).Info("UserLogout")
+	assert.Equal(t, uint64(5), sumViolations(writer.Violations()))
+
+	// behind other writers too
+	multi := NewMultiWriter(Sink{Writer: NewSamplingWriter(writer)})
+	NewWitCustomWriter(rsFields, multi).Info("UserSignup")
+	assert.Equal(t, uint64(6), sumViolations(writer.Violations()))
+}
+
+func sumViolations(violations map[string]uint64) uint64 {
+	var sum uint64
+	for _, count := range violations {
+		sum += count
+	}
+	return sum
+}
+
+func Test_ValidatingWriter_Warn(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	systemLog.SetOutput(stderr)
+	defer systemLog.SetOutput(os.Stderr)
+
+	writer := NewValidatingWriter(NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+	}), func(conf *ValidatingWriterConfig) {
+		conf.Registry = NewSchemaRegistry(surveySavedSchema)
+		conf.Warn = true
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("survey_saved")
+	assert.Contains(t, stderr.String(), "log schema violation: event 'survey_saved' key 'survey_id' is required")
+}
+
+func Test_ValidatingWriter_Defaults(t *testing.T) {
+	t.Setenv("APP_ENV", "development")
+	next := NewWriter(func(conf *WriterConfig) {
+		conf.Level = WarnSev
+	})
+	writer := NewValidatingWriter(next)
+
+	assert.True(t, writer.warn)
+	assert.NotNil(t, writer.Registry())
+	assert.False(t, writer.IsEnabled(InfoSev))
+	assert.Same(t, next.Redactor(), writer.Redactor())
+	assert.Nil(t, writer.Flush(context.Background()))
+
+	t.Setenv("APP_ENV", "production")
+	assert.False(t, NewValidatingWriter(next).warn)
+}
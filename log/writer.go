@@ -1,9 +1,11 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"github.com/cultureamp/glamplify/env"
 	"io"
+	systemLog "log"
 	"os"
 	"strings"
 	"sync"
@@ -16,7 +18,13 @@ type WriterConfig struct {
 	Output     io.Writer
 	OmitEmpty  bool
 	UseColours bool
+	Format     string
 	Level      string
+	Levels     *LevelSwitch
+	DebugList  *DebugList
+	Redactor   *Redactor
+	// FatalHandler is called after a FATAL entry is written. Default PanicOnFatal.
+	FatalHandler FatalHandler
 }
 
 // FieldWriter wraps the standard library writer and add structured types as quoted key value pairs
@@ -27,7 +35,11 @@ type FieldWriter struct {
 	output    io.Writer
 	omitempty bool
 	useColors bool
-	level     int
+	format    string
+	levels    *LevelSwitch
+	debugList *DebugList
+	redactor  *Redactor
+	onFatal   FatalHandler
 }
 
 // Writer defines an interface for writing log messages
@@ -36,21 +48,49 @@ type Writer interface {
 	IsEnabled(sev string) bool
 }
 
+// ForceWriter is implemented by writers that can write an entry regardless of the level, sampling etc.
+// Used to write a RequestBuffer's entries when a request fails.
+type ForceWriter interface {
+	ForceWriteFields(sev string, system Fields, fields ...Fields) string
+}
+
+// RedactingWriter is implemented by writers that supply their own Redactor.
+// Writers that don't implement it get the default Redactor configured from the environment.
+type RedactingWriter interface {
+	Redactor() *Redactor
+}
+
+// flusher is implemented by writers that queue or batch entries (eg. AsyncWriter)
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
 // NewWriter creates a new FieldWriter. The optional configure func lets you set values on the underlying standard writer.
 // Useful for CLI apps that want to direct logging to a file or stderr
 // eg. SetOutput
 func NewWriter(configure ...func(*WriterConfig)) *FieldWriter { // https://dave.cheney.net/2014/10/17/functional-options-for-friendly-apis
-	writer := &FieldWriter{}
-	conf := WriterConfig{
+	conf := newWriterConfig()
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	return newFieldWriter(conf)
+}
+
+func newWriterConfig() WriterConfig {
+	return WriterConfig{
 		Output:     os.Stdout,
 		OmitEmpty:  env.GetBool(env.LogOmitEmpty, false),
 		UseColours: env.GetBool(env.LogUseColours, false),
+		Format:     env.GetString(env.LogFormat, JSONFormat),
 		Level:      env.GetString(env.LogLevel, DebugSev),
+		DebugList:  defaultDebugList,
+		Redactor:   defaultRedactor,
 	}
-	for _, config := range configure {
-		config(&conf)
-	}
+}
 
+func newFieldWriter(conf WriterConfig) *FieldWriter {
+	writer := &FieldWriter{}
 	writer.mutex = &sync.Mutex{}
 	writer.mutex.Lock()
 	defer writer.mutex.Unlock()
@@ -59,31 +99,120 @@ func NewWriter(configure ...func(*WriterConfig)) *FieldWriter { // https://dave.
 	writer.output = conf.Output
 	writer.omitempty = conf.OmitEmpty
 	writer.useColors = conf.UseColours
-	writer.level = writer.levelMap.StringToLevel(conf.Level)
+	writer.format = conf.Format
+	writer.levels = conf.Levels
+	if writer.levels == nil {
+		writer.levels = newLevelSwitchFromConfig(conf.Level)
+	}
+	writer.debugList = conf.DebugList
+	writer.redactor = conf.Redactor
+	writer.onFatal = conf.FatalHandler
 
 	return writer
 }
 
 // WriteFields returns a json string for the given severity and system and user Fields
 func (writer *FieldWriter) WriteFields(sev string, system Fields, fields ...Fields) string {
-	merged := Fields{}
-	properties := merged.Merge(fields...)
-	if len(properties) > 0 {
-		system[Properties] = properties
-	}
-	json := system.ToSnakeCase().ToJSON(writer.omitempty)
+	scope := newLogScope(system)
+	json, output := writer.formatFields(system, fields...)
 
-	if writer.IsEnabled(sev) {
-		writer.write(sev, json)
+	if writer.isEnabledFor(scope, sev) {
+		writer.write(sev, output)
 	}
 	return json
 }
 
-// IsEnabled returns true if the sev is enabled, false otherwise
+// ForceWriteFields writes the entry regardless of the level and returns it as a json string
+func (writer *FieldWriter) ForceWriteFields(sev string, system Fields, fields ...Fields) string {
+	json, output := writer.formatFields(system, fields...)
+	writer.write(sev, output)
+	return json
+}
+
+// Redactor returns the Redactor used to mask sensitive values before they reach this writer
+func (writer *FieldWriter) Redactor() *Redactor {
+	return writer.redactor
+}
+
+// Levels returns the LevelSwitch so the level and override rules can be changed at runtime
+func (writer *FieldWriter) Levels() *LevelSwitch {
+	return writer.levels
+}
+
+// DebugList returns the customers and users for which every severity is written
+func (writer *FieldWriter) DebugList() *DebugList {
+	return writer.debugList
+}
+
+// FatalHandler returns the FatalHandler configured for this writer, or nil for the default
+func (writer *FieldWriter) FatalHandler() FatalHandler {
+	return writer.onFatal
+}
+
+// IsEnabled returns true if the sev is enabled for any event or package, false otherwise
 func (writer FieldWriter) IsEnabled(sev string) bool {
-	level := writer.levelMap.StringToLevel(sev)
+	return writer.levels.IsEnabled(sev)
+}
+
+func (writer FieldWriter) isEnabledFor(scope logScope, sev string) bool {
+	return writer.levels.IsEnabledFor(scope.event, scope.pkg, sev) ||
+		writer.debugList.matches(scope.customer, scope.user)
+}
+
+// writeFields uses ForceWriteFields if force is set and the writer supports it, otherwise WriteFields
+func writeFields(writer Writer, force bool, sev string, system Fields, fields ...Fields) string {
+	if force {
+		if fw, ok := writer.(ForceWriter); ok {
+			return fw.ForceWriteFields(sev, system, fields...)
+		}
+	}
+
+	return writer.WriteFields(sev, system, fields...)
+}
+
+// newLevelSwitchFromConfig reports an invalid level rather than silently writing everything, and uses INFO instead
+func newLevelSwitchFromConfig(level string) *LevelSwitch {
+	if _, err := ParseLevel(level); err != nil {
+		systemLog.Printf("%s, using %s", err.Error(), InfoSev)
+		level = InfoSev
+	}
+
+	levels := NewLevelSwitch(level)
+	if err := levels.SetRules(levelRulesFromEnv()...); err != nil {
+		systemLog.Printf("ignoring invalid %s: %s", env.LogLevelRules, err.Error())
+	}
+	return levels
+}
+
+func levelRulesFromEnv() []LevelRule {
+	rules, err := ParseLevelRules(env.GetString(env.LogLevelRules, ""))
+	if err != nil {
+		systemLog.Printf("ignoring invalid %s: %s", env.LogLevelRules, err.Error())
+	}
+
+	return rules
+}
+
+// entryJSON returns the entry as json, as FieldWriter would write it, for entries that aren't written
+func entryJSON(system Fields, properties Fields) string {
+	entry := system.ToSnakeCase()
+	if len(properties) > 0 {
+		entry[Properties] = properties.ToSnakeCase()
+	}
+	return entry.serialize().marshal(false)
+}
+
+// formatFields returns the entry as json, and in the configured output format
+func (writer *FieldWriter) formatFields(system Fields, fields ...Fields) (string, string) {
+	merged := Fields{}
+	properties := merged.Merge(fields...)
+	if len(properties) > 0 {
+		system[Properties] = properties
+	}
 
-	return writer.levelMap.ShouldLogLevel(writer.level, level)
+	entry := system.ToSnakeCase().serialize()
+	json := entry.marshal(writer.omitempty)
+	return json, formatEntry(writer.format, entry, json)
 }
 
 func (writer *FieldWriter) write(sev string, json string) {
@@ -98,21 +227,9 @@ func (writer *FieldWriter) write(sev string, json string) {
 		// Also we purposely print with double NewLines (1 in the string and an extra one when printing)
 		// to make it easy to separate different log lines...
 		color.SetOutput(writer.output)
-		level := writer.levelMap.StringToLevel(sev)
-		switch level {
-		case DebugLevel:
-			color.Debug.Println(json)
-		case InfoLevel:
-			color.Info.Println(json)
-		case WarnLevel:
-			color.Warn.Println(json)
-		case ErrorLevel:
-			color.Error.Println(json)
-		case FatalLevel:
-			color.Danger.Println(json)
-		case AuditLevel:
-			color.Notice.Println(json)
-		default:
+		if theme := levelTheme(sev); theme != nil {
+			theme.Println(json)
+		} else {
 			color.Print(json)
 		}
 	} else {
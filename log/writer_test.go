@@ -2,8 +2,11 @@ package log
 
 import (
 	"bytes"
+	systemLog "log"
+	"os"
 	"testing"
 
+	"github.com/cultureamp/glamplify/env"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -72,3 +75,52 @@ func Test_WriteFields_IsEnabled(t *testing.T) {
 	ok = writer.IsEnabled(AuditSev)
 	assert.True(t, ok)
 }
+
+func Test_NewWriter_InvalidLevel(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	systemLog.SetOutput(stderr)
+	defer systemLog.SetOutput(os.Stderr)
+
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.Level = "DEBGU"
+	})
+
+	assert.Equal(t, InfoSev, writer.Levels().Level())
+	assert.False(t, writer.IsEnabled(DebugSev))
+	assert.True(t, writer.IsEnabled(InfoSev))
+	assert.Contains(t, stderr.String(), "unknown log level 'DEBGU'")
+	assert.Contains(t, stderr.String(), "using INFO")
+}
+
+func Test_NewWriter_InvalidLevelRule(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	systemLog.SetOutput(stderr)
+	defer systemLog.SetOutput(os.Stderr)
+
+	os.Setenv(env.LogLevelRules, "event:authz_=DEBGU")
+	defer os.Unsetenv(env.LogLevelRules)
+
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = &bytes.Buffer{}
+		conf.Level = "warn"
+	})
+
+	assert.Equal(t, WarnSev, writer.Levels().Level())
+	assert.Empty(t, writer.Levels().Rules())
+	assert.Contains(t, stderr.String(), "ignoring invalid LOG_LEVEL_RULES")
+}
+
+func Test_WriteFields_Colours(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.UseColours = true
+	})
+
+	writer.WriteFields(NoticeSev, Fields{"event": "coloured"})
+	writer.WriteFields("UNREGISTERED", Fields{"event": "plain"})
+
+	assert.Contains(t, memBuffer.String(), "coloured")
+	assert.Contains(t, memBuffer.String(), "plain")
+}
@@ -0,0 +1,357 @@
+package otel
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+)
+
+// OTel severity numbers, see https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+const (
+	severityUnspecified = 0
+	severityTrace       = 1
+	severityDebug       = 5
+	severityInfo        = 9
+	severityWarn        = 13
+	severityError       = 17
+	severityFatal       = 21
+)
+
+// resourceKeys maps our system keys onto the OTel resource semantic conventions
+var resourceKeys = map[string]string{
+	log.Product:      "service.namespace",
+	log.App:          "service.name",
+	log.AppVer:       "service.version",
+	log.Farm:         "deployment.environment",
+	log.AwsRegion:    "cloud.region",
+	log.AwsAccountID: "cloud.account.id",
+	log.Resource:     "host.name",
+	log.Os:           "os.type",
+}
+
+// OTLP/HTTP JSON encoding of the logs data model, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+type exportLogsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber,omitempty"`
+	SeverityText         string     `json:"severityText,omitempty"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	Flags                uint32     `json:"flags,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue has exactly one of its values set. 64 bit ints are written as strings, as per the protobuf JSON mapping.
+type anyValue struct {
+	StringValue *string      `json:"stringValue,omitempty"`
+	BoolValue   *bool        `json:"boolValue,omitempty"`
+	IntValue    *string      `json:"intValue,omitempty"`
+	DoubleValue *float64     `json:"doubleValue,omitempty"`
+	ArrayValue  *arrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *kvlistValue `json:"kvlistValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type kvlistValue struct {
+	Values []keyValue `json:"values"`
+}
+
+// record is a log record and the resource it came from
+type record struct {
+	resource    []keyValue
+	resourceKey string
+	logRecord   logRecord
+}
+
+// newRecord maps a serialized entry (see log.Fields.Serialize) onto the OTel log data model.
+// The product, app, farm etc. become resource attributes, the message (or event if there isn't one) the body,
+// and the trace_id the trace and span ids. Everything else is kept as attributes with the same keys.
+func newRecord(sev string, entry log.Fields, observed time.Time) record {
+	rec := record{}
+	lr := logRecord{
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       severityNumber(sev),
+		SeverityText:         sev,
+	}
+
+	if t, ok := entry[log.Time].(string); ok {
+		if parsed, err := time.Parse(log.RFC3339Milli, t); err == nil {
+			lr.TimeUnixNano = strconv.FormatInt(parsed.UnixNano(), 10)
+			delete(entry, log.Time)
+		}
+	}
+	delete(entry, log.Severity)
+
+	for key, name := range resourceKeys {
+		if value, ok := entry[key]; ok {
+			if s, isString := value.(string); !isString || s != "" {
+				rec.resource = append(rec.resource, keyValue{Key: name, Value: toAnyValue(value)})
+			}
+			delete(entry, key)
+		}
+	}
+	sortKeyValues(rec.resource)
+	resourceKey, _ := json.Marshal(rec.resource)
+	rec.resourceKey = string(resourceKey)
+
+	if traceID, ok := entry[log.TraceID].(string); ok {
+		lr.TraceID, lr.SpanID, lr.Flags = parseTraceID(traceID)
+	}
+
+	event, _ := entry[log.Event].(string)
+	body := event
+	if properties, ok := entry[log.Properties].(log.Fields); ok {
+		if message, _ := properties[log.Message].(string); message != "" {
+			body = message
+			delete(properties, log.Message)
+			if len(properties) == 0 {
+				delete(entry, log.Properties)
+			}
+		}
+	}
+	lr.Body = stringValue(body)
+
+	lr.Attributes = toKeyValues(entry)
+	rec.logRecord = lr
+	return rec
+}
+
+// severityNumber maps our levels, including any added with log.RegisterLevel, onto the OTel ranges
+func severityNumber(sev string) int {
+	if sev == log.AuditSev {
+		// AUDIT entries are informational, the severityText keeps them apart
+		return severityInfo
+	}
+
+	level, err := log.ParseLevel(sev)
+	switch {
+	case err != nil:
+		return severityUnspecified
+	case level < log.DebugLevel:
+		return severityTrace
+	case level < log.InfoLevel:
+		return severityDebug
+	case level < log.WarnLevel:
+		return severityInfo
+	case level < log.ErrorLevel:
+		return severityWarn
+	case level < log.FatalLevel:
+		return severityError
+	}
+	return severityFatal
+}
+
+// parseTraceID returns the OTel trace id, span id and trace flags from an X-Ray trace header
+// (eg. "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"), an X-Ray trace id
+// (eg. "1-5759e988-bd862e3fe1be46a994272793") or a W3C trace id. Anything else returns empty ids.
+func parseTraceID(traceID string) (string, string, uint32) {
+	root := traceID
+	parent := ""
+	var flags uint32
+	if strings.Contains(traceID, "=") {
+		root = ""
+		for _, part := range strings.Split(traceID, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "Root":
+				root = value
+			case "Parent":
+				parent = value
+			case "Sampled":
+				if value == "1" {
+					flags = 1
+				}
+			}
+		}
+	}
+
+	// X-Ray trace ids are the version, the epoch in hex and 96 random bits, eg. 1-5759e988-bd862e3fe1be46a994272793
+	if parts := strings.Split(root, "-"); len(parts) == 3 && parts[0] == "1" {
+		root = parts[1] + parts[2]
+	}
+
+	root = strings.ToLower(root)
+	if !isHexID(root, 32) {
+		return "", "", 0
+	}
+	parent = strings.ToLower(parent)
+	if !isHexID(parent, 16) {
+		parent = ""
+	}
+	return root, parent, flags
+}
+
+// isHexID returns true if id is length hex characters and not all zeros, which OTel treats as invalid
+func isHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// toAnyValue converts a serialized value (see log.Fields.Serialize) to an anyValue
+func toAnyValue(value interface{}) anyValue {
+	switch v := value.(type) {
+	case nil:
+		return anyValue{}
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case int32:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case uint:
+		return uintValue(uint64(v))
+	case uint64:
+		return uintValue(v)
+	case uint32:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint8:
+		return intValue(int64(v))
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case []string:
+		values := make([]anyValue, 0, len(v))
+		for _, item := range v {
+			values = append(values, stringValue(item))
+		}
+		return anyValue{ArrayValue: &arrayValue{Values: values}}
+	case log.Fields:
+		return anyValue{KvlistValue: &kvlistValue{Values: toKeyValues(v)}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return intValue(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return anyValue{DoubleValue: &f}
+		}
+		return stringValue(v.String())
+	case []interface{}:
+		values := make([]anyValue, 0, len(v))
+		for _, item := range v {
+			values = append(values, toAnyValue(item))
+		}
+		return anyValue{ArrayValue: &arrayValue{Values: values}}
+	case map[string]interface{}:
+		return anyValue{KvlistValue: &kvlistValue{Values: toKeyValues(v)}}
+	}
+
+	// eg. structs, json.Marshalers and slices of numbers, which are mapped the way they are written as json
+	marshalled, err := json.Marshal(value)
+	if err != nil {
+		return stringValue(fmt.Sprintf("[unserializable %T]", value))
+	}
+	decoder := json.NewDecoder(bytes.NewReader(marshalled))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return stringValue(string(marshalled))
+	}
+	return toAnyValue(decoded)
+}
+
+func intValue(i int64) anyValue {
+	s := strconv.FormatInt(i, 10)
+	return anyValue{IntValue: &s}
+}
+
+// uintValue writes uints too big for an int64 as a double, like json.Number does
+func uintValue(u uint64) anyValue {
+	if u > math.MaxInt64 {
+		f := float64(u)
+		return anyValue{DoubleValue: &f}
+	}
+	return intValue(int64(u))
+}
+
+func toKeyValues(fields map[string]interface{}) []keyValue {
+	kvs := make([]keyValue, 0, len(fields))
+	for key, value := range fields {
+		kvs = append(kvs, keyValue{Key: key, Value: toAnyValue(value)})
+	}
+	sortKeyValues(kvs)
+	return kvs
+}
+
+func sortKeyValues(kvs []keyValue) {
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+// newExportLogsRequest groups the records by resource, keeping the order they were written in
+func newExportLogsRequest(scopeName string, records []record) exportLogsRequest {
+	request := exportLogsRequest{}
+	index := map[string]int{}
+	for _, rec := range records {
+		i, ok := index[rec.resourceKey]
+		if !ok {
+			i = len(request.ResourceLogs)
+			index[rec.resourceKey] = i
+			request.ResourceLogs = append(request.ResourceLogs, resourceLogs{
+				Resource:  resource{Attributes: rec.resource},
+				ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName}}},
+			})
+		}
+		sl := &request.ResourceLogs[i].ScopeLogs[0]
+		sl.LogRecords = append(sl.LogRecords, rec.logRecord)
+	}
+	return request
+}
@@ -0,0 +1,178 @@
+package otel
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OTel_ParseTraceID(t *testing.T) {
+	traceID, spanID, flags := parseTraceID("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", traceID)
+	assert.Equal(t, "53995c3f42cd8ad8", spanID)
+	assert.Equal(t, uint32(1), flags)
+
+	traceID, spanID, flags = parseTraceID("1-5759e988-bd862e3fe1be46a994272793")
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", traceID)
+	assert.Empty(t, spanID)
+	assert.Equal(t, uint32(0), flags)
+
+	traceID, _, _ = parseTraceID("4BF92F3577B34DA6A3CE929D0E0E4736")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+
+	traceID, spanID, _ = parseTraceID("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=not-hex;Sampled=0")
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", traceID)
+	assert.Empty(t, spanID)
+}
+
+func Test_OTel_ParseTraceID_Invalid(t *testing.T) {
+	for _, id := range []string{"", "1-2-3", "trace1", "00000000000000000000000000000000", "Parent=53995c3f42cd8ad8"} {
+		traceID, spanID, flags := parseTraceID(id)
+		assert.Empty(t, traceID, id)
+		assert.Empty(t, spanID, id)
+		assert.Equal(t, uint32(0), flags, id)
+	}
+}
+
+func Test_OTel_SeverityNumber(t *testing.T) {
+	assert.Equal(t, 1, severityNumber(log.TraceSev))
+	assert.Equal(t, 5, severityNumber(log.DebugSev))
+	assert.Equal(t, 9, severityNumber(log.InfoSev))
+	assert.Equal(t, 9, severityNumber(log.NoticeSev))
+	assert.Equal(t, 9, severityNumber(log.AuditSev))
+	assert.Equal(t, 13, severityNumber(log.WarnSev))
+	assert.Equal(t, 17, severityNumber(log.ErrorSev))
+	assert.Equal(t, 21, severityNumber(log.FatalSev))
+	assert.Equal(t, 0, severityNumber("BAD"))
+}
+
+func Test_OTel_NewRecord(t *testing.T) {
+	entry := log.Fields{
+		"time":        "2020-09-14T01:02:03.456Z",
+		"event":       "survey_created",
+		"severity":    "INFO",
+		"product":     "engagement",
+		"app":         "murmur",
+		"farm":        "production",
+		"app_version": "1.2.3",
+		"aws_region":  "us-west-2",
+		"trace_id":    "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		"customer":    "hooli",
+		"properties": log.Fields{
+			"message": "hello",
+			"count":   3,
+			"ratio":   0.5,
+			"ok":      true,
+			"tags":    []string{"a"},
+			"nested":  log.Fields{"k": "v"},
+			"none":    nil,
+		},
+	}
+	observed := time.Date(2020, 9, 14, 1, 2, 4, 0, time.UTC)
+
+	rec := newRecord(log.InfoSev, entry, observed)
+
+	lr := rec.logRecord
+	assert.Equal(t, "1600045323456000000", lr.TimeUnixNano)
+	assert.Equal(t, "1600045324000000000", lr.ObservedTimeUnixNano)
+	assert.Equal(t, 9, lr.SeverityNumber)
+	assert.Equal(t, "INFO", lr.SeverityText)
+	assert.Equal(t, "hello", *lr.Body.StringValue)
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", lr.TraceID)
+	assert.Equal(t, "53995c3f42cd8ad8", lr.SpanID)
+	assert.Equal(t, uint32(1), lr.Flags)
+
+	assert.Equal(t, map[string]string{
+		"cloud.region":           "us-west-2",
+		"deployment.environment": "production",
+		"service.name":           "murmur",
+		"service.namespace":      "engagement",
+		"service.version":        "1.2.3",
+	}, stringAttributes(rec.resource))
+
+	attributes := map[string]anyValue{}
+	for _, kv := range lr.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	assert.NotContains(t, attributes, log.Time)
+	assert.NotContains(t, attributes, log.Severity)
+	assert.NotContains(t, attributes, log.App)
+	assert.Equal(t, "survey_created", *attributes[log.Event].StringValue)
+	assert.Equal(t, "hooli", *attributes[log.Customer].StringValue)
+	assert.Contains(t, attributes, log.TraceID)
+
+	properties := map[string]anyValue{}
+	for _, kv := range attributes[log.Properties].KvlistValue.Values {
+		properties[kv.Key] = kv.Value
+	}
+	assert.NotContains(t, properties, log.Message)
+	assert.Equal(t, "3", *properties["count"].IntValue)
+	assert.Equal(t, 0.5, *properties["ratio"].DoubleValue)
+	assert.True(t, *properties["ok"].BoolValue)
+	assert.Equal(t, "a", *properties["tags"].ArrayValue.Values[0].StringValue)
+	assert.Equal(t, "v", *properties["nested"].KvlistValue.Values[0].Value.StringValue)
+	assert.Equal(t, anyValue{}, properties["none"])
+}
+
+func Test_OTel_NewRecord_EventIsBody(t *testing.T) {
+	entry := log.Fields{"event": "survey_created", "app": "", "trace_id": "1-2-3", "properties": log.Fields{"message": "", "count": 3}}
+
+	rec := newRecord(log.WarnSev, entry, time.Now())
+
+	assert.Equal(t, "survey_created", *rec.logRecord.Body.StringValue)
+	assert.Empty(t, rec.logRecord.TraceID)
+	assert.Empty(t, rec.logRecord.TimeUnixNano)
+	assert.Empty(t, rec.resource)
+}
+
+func Test_OTel_NewExportLogsRequest_GroupsByResource(t *testing.T) {
+	a1 := newRecord(log.InfoSev, log.Fields{"event": "a1", "app": "a"}, time.Now())
+	b1 := newRecord(log.InfoSev, log.Fields{"event": "b1", "app": "b"}, time.Now())
+	a2 := newRecord(log.InfoSev, log.Fields{"event": "a2", "app": "a"}, time.Now())
+
+	request := newExportLogsRequest("test", []record{a1, b1, a2})
+
+	assert.Len(t, request.ResourceLogs, 2)
+	assert.Equal(t, "a", stringAttributes(request.ResourceLogs[0].Resource.Attributes)["service.name"])
+	assert.Equal(t, "test", request.ResourceLogs[0].ScopeLogs[0].Scope.Name)
+	assert.Len(t, request.ResourceLogs[0].ScopeLogs[0].LogRecords, 2)
+	assert.Equal(t, "a2", *request.ResourceLogs[0].ScopeLogs[0].LogRecords[1].Body.StringValue)
+	assert.Len(t, request.ResourceLogs[1].ScopeLogs[0].LogRecords, 1)
+
+	body, err := json.Marshal(request)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"a"}}]}`)
+	assert.Contains(t, string(body), `"body":{"stringValue":"a1"}`)
+}
+
+func Test_OTel_ToAnyValue(t *testing.T) {
+	assert.Equal(t, "-7", *toAnyValue(int8(-7)).IntValue)
+	assert.Equal(t, "42", *toAnyValue(uint32(42)).IntValue)
+	assert.Equal(t, "9007199254740993", *toAnyValue(int64(9007199254740993)).IntValue)
+	assert.Equal(t, float64(math.MaxUint64), *toAnyValue(uint64(math.MaxUint64)).DoubleValue)
+	assert.Equal(t, 1.5, *toAnyValue(float32(1.5)).DoubleValue)
+	assert.Equal(t, anyValue{}, toAnyValue(nil))
+
+	// eg. structs and slices of numbers are mapped the way they are written as json
+	numbers := toAnyValue([]int{1, 2})
+	assert.Equal(t, "2", *numbers.ArrayValue.Values[1].IntValue)
+	survey := toAnyValue(struct {
+		ID    string `json:"id"`
+		Count int    `json:"count"`
+	}{ID: "abc", Count: 3})
+	assert.Equal(t, map[string]string{"id": "abc"}, stringAttributes(survey.KvlistValue.Values))
+}
+
+func stringAttributes(kvs []keyValue) map[string]string {
+	attributes := map[string]string{}
+	for _, kv := range kvs {
+		if kv.Value.StringValue != nil {
+			attributes[kv.Key] = *kv.Value.StringValue
+		}
+	}
+	return attributes
+}
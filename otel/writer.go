@@ -0,0 +1,305 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	systemLog "log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cultureamp/glamplify/env"
+	"github.com/cultureamp/glamplify/log"
+)
+
+const (
+	contentTypeHeader   = "Content-Type"
+	applicationJSONType = "application/json"
+
+	defaultEndpoint      = "http://localhost:4318"
+	logsPath             = "/v1/logs"
+	defaultTimeoutMS     = 10000
+	defaultBatchSize     = 512
+	defaultScheduleDelay = 1000
+	defaultMaxQueueSize  = 2048
+	defaultScopeName     = "github.com/cultureamp/glamplify"
+)
+
+// OTLPWriter sends logging output to an OpenTelemetry collector as OTLP/HTTP JSON.
+// Entries are mapped onto the OTel log data model and exported in batches, either when BatchSize entries
+// are waiting or every FlushInterval. At most MaxQueueSize entries wait, so entries are dropped rather than using up
+// all the memory while the collector is down. Call Flush before a Lambda freezes and Close on shutdown so entries are not lost.
+type OTLPWriter struct {
+	// PUBLIC
+
+	// Endpoint URL: OTEL_EXPORTER_OTLP_LOGS_ENDPOINT, or OTEL_EXPORTER_OTLP_ENDPOINT + "/v1/logs", or http://localhost:4318/v1/logs (default)
+	Endpoint string
+
+	// Headers sent with each export, eg. for authentication. Default OTEL_EXPORTER_OTLP_HEADERS ("key1=value1,key2=value2")
+	Headers map[string]string
+
+	// Timeout on HTTP requests. Default OTEL_EXPORTER_OTLP_TIMEOUT (ms) or 10 seconds
+	Timeout time.Duration
+
+	// BatchSize is how many entries are sent in each export. Default OTEL_BLRP_MAX_EXPORT_BATCH_SIZE or 512
+	BatchSize int
+
+	// MaxQueueSize is how many entries can wait to be exported, more are dropped. Default OTEL_BLRP_MAX_QUEUE_SIZE or 2048
+	MaxQueueSize int
+
+	// FlushInterval is how often waiting entries are exported. Default OTEL_BLRP_SCHEDULE_DELAY (ms) or 1 second
+	FlushInterval time.Duration
+
+	// ScopeName is the instrumentation scope of the exported records. Default "github.com/cultureamp/glamplify"
+	ScopeName string
+
+	// OmitEmpty will remove empty fields before sending
+	OmitEmpty bool
+
+	// Level we are logging, DEBUG, INFO, etc.
+	Level string
+
+	// PRIVATE
+
+	client   *http.Client
+	leveller *log.Leveller
+
+	mutex   sync.Mutex
+	pending []record
+	closed  bool
+	dropped uint64
+
+	// only one export at a time, so batches arrive in order
+	exportMutex sync.Mutex
+
+	full chan struct{}
+	done chan struct{}
+	wait sync.WaitGroup
+}
+
+// NewOTLPWriter creates a new OTLPWriter. The optional configure func lets you set values on the underlying writer.
+func NewOTLPWriter(configure ...func(*OTLPWriter)) *OTLPWriter {
+	writer := &OTLPWriter{
+		Endpoint:      env.GetString(env.OtelExporterLogsEndpoint, strings.TrimSuffix(env.GetString(env.OtelExporterEndpoint, defaultEndpoint), "/")+logsPath),
+		Headers:       parseHeaders(env.GetString(env.OtelExporterHeaders, "")),
+		Timeout:       time.Millisecond * time.Duration(env.GetInt(env.OtelExporterTimeout, defaultTimeoutMS)),
+		BatchSize:     env.GetInt(env.OtelLogBatchSize, defaultBatchSize),
+		MaxQueueSize:  env.GetInt(env.OtelLogMaxQueueSize, defaultMaxQueueSize),
+		FlushInterval: time.Millisecond * time.Duration(env.GetInt(env.OtelLogScheduleDelay, defaultScheduleDelay)),
+		ScopeName:     defaultScopeName,
+		OmitEmpty:     env.GetBool(env.LogOmitEmpty, false),
+		Level:         env.GetString(env.LogLevel, log.DebugSev),
+		leveller:      log.NewLevelMap(),
+		full:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+
+	for _, config := range configure {
+		config(writer)
+	}
+	if _, err := log.ParseLevel(writer.Level); err != nil {
+		systemLog.Printf("%s, using %s", err.Error(), log.InfoSev)
+		writer.Level = log.InfoSev
+	}
+	if writer.BatchSize <= 0 {
+		writer.BatchSize = defaultBatchSize
+	}
+	if writer.MaxQueueSize <= 0 {
+		writer.MaxQueueSize = defaultMaxQueueSize
+	}
+	if writer.FlushInterval <= 0 {
+		writer.FlushInterval = time.Millisecond * defaultScheduleDelay
+	}
+	if writer.Timeout <= 0 {
+		writer.Timeout = time.Millisecond * defaultTimeoutMS
+	}
+	writer.client = &http.Client{
+		Timeout: writer.Timeout,
+	}
+
+	writer.wait.Add(1)
+	go writer.run()
+
+	return writer
+}
+
+// WriteFields returns a json string for the given severity and system and user Fields and queues it to be exported
+func (writer *OTLPWriter) WriteFields(sev string, system log.Fields, fields ...log.Fields) string {
+	merged := log.Fields{}
+	properties := merged.Merge(fields...)
+	if len(properties) > 0 {
+		system[log.Properties] = properties
+	}
+
+	entry := system.ToSnakeCase().Serialize(writer.OmitEmpty)
+	json := entry.ToJSON(false)
+	if writer.IsEnabled(sev) {
+		// after ToJSON, as newRecord takes the keys it maps out of the entry
+		writer.enqueue(newRecord(sev, entry, time.Now()))
+	}
+	return json
+}
+
+// IsEnabled returns true if the sev is enabled, false otherwise
+func (writer *OTLPWriter) IsEnabled(sev string) bool {
+	return writer.leveller.ShouldLogSeverity(writer.Level, sev)
+}
+
+// Dropped returns the number of entries dropped because the queue was full, since the last time it was reported
+func (writer *OTLPWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+// Flush exports the entries waiting when it is called, returning an error if the collector couldn't be reached or rejected them.
+// Entries written while it is exporting are left for the next Flush, so it returns even if entries keep being written.
+func (writer *OTLPWriter) Flush(ctx context.Context) error {
+	writer.exportMutex.Lock()
+	defer writer.exportMutex.Unlock()
+
+	// only one export at a time, so the first remaining entries are the ones waiting now
+	writer.mutex.Lock()
+	remaining := len(writer.pending)
+	writer.mutex.Unlock()
+
+	for remaining > 0 {
+		batch := writer.nextBatch(remaining)
+		if len(batch) == 0 {
+			return nil
+		}
+		remaining -= len(batch)
+		if err := writer.export(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the background go routine and exports all waiting entries.
+// Entries written after Close are exported by the next Flush.
+func (writer *OTLPWriter) Close() error {
+	writer.mutex.Lock()
+	if !writer.closed {
+		writer.closed = true
+		close(writer.done)
+	}
+	writer.mutex.Unlock()
+	writer.wait.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), writer.Timeout)
+	defer cancel()
+	return writer.Flush(ctx)
+}
+
+func (writer *OTLPWriter) enqueue(rec record) {
+	writer.mutex.Lock()
+	if len(writer.pending) >= writer.MaxQueueSize {
+		writer.mutex.Unlock()
+		atomic.AddUint64(&writer.dropped, 1)
+		return
+	}
+	writer.pending = append(writer.pending, rec)
+	full := len(writer.pending) >= writer.BatchSize
+	writer.mutex.Unlock()
+
+	if full {
+		select {
+		case writer.full <- struct{}{}:
+		default: // already signalled
+		}
+	}
+}
+
+func (writer *OTLPWriter) run() {
+	defer writer.wait.Done()
+
+	ticker := time.NewTicker(writer.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-writer.done:
+			return
+		case <-ticker.C:
+		case <-writer.full:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), writer.Timeout)
+		if err := writer.Flush(ctx); err != nil {
+			systemLog.Printf("failed to export logs to '%s': %s", writer.Endpoint, err.Error())
+		}
+		cancel()
+
+		if dropped := atomic.SwapUint64(&writer.dropped, 0); dropped > 0 {
+			systemLog.Printf("dropped %d log records as more than %d were waiting to be exported to '%s'", dropped, writer.MaxQueueSize, writer.Endpoint)
+		}
+	}
+}
+
+// nextBatch takes up to max waiting entries, and no more than BatchSize
+func (writer *OTLPWriter) nextBatch(max int) []record {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	n := len(writer.pending)
+	if n > max {
+		n = max
+	}
+	if n > writer.BatchSize {
+		n = writer.BatchSize
+	}
+	batch := writer.pending[:n:n]
+	writer.pending = writer.pending[n:]
+	return batch
+}
+
+// export posts the batch to the collector. The batch is dropped if it fails, so a broken collector can't use up all the memory.
+func (writer *OTLPWriter) export(ctx context.Context, batch []record) error {
+	body, err := json.Marshal(newExportLogsRequest(writer.ScopeName, batch))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, writer.Endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(contentTypeHeader, applicationJSONType)
+	for key, value := range writer.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := writer.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector returned %s for %d log records", resp.Status, len(batch))
+	}
+	return nil
+}
+
+// parseHeaders parses OTEL_EXPORTER_OTLP_HEADERS, eg. "api-key=secret,tenant=hooli". Values may be url encoded.
+func parseHeaders(headers string) map[string]string {
+	parsed := map[string]string{}
+	for _, header := range strings.Split(headers, ",") {
+		key, value, ok := strings.Cut(header, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = unescaped
+		}
+		parsed[key] = strings.TrimSpace(value)
+	}
+	return parsed
+}
@@ -0,0 +1,296 @@
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// collector is a local OTLP/HTTP collector that keeps the requests it receives
+type collector struct {
+	server   *httptest.Server
+	mutex    sync.Mutex
+	requests []exportLogsRequest
+	headers  []http.Header
+	status   int
+	received chan struct{}
+}
+
+func newCollector() *collector {
+	c := &collector{
+		status:   http.StatusOK,
+		received: make(chan struct{}, 16),
+	}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := exportLogsRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+
+		c.mutex.Lock()
+		c.requests = append(c.requests, request)
+		c.headers = append(c.headers, r.Header.Clone())
+		status := c.status
+		c.mutex.Unlock()
+
+		w.WriteHeader(status)
+		c.received <- struct{}{}
+	}))
+	return c
+}
+
+func (c *collector) records() []logRecord {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var records []logRecord
+	for _, request := range c.requests {
+		for _, rl := range request.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func (c *collector) wait(t *testing.T) {
+	select {
+	case <-c.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector didn't receive an export")
+	}
+}
+
+func newTestWriter(c *collector, configure ...func(*OTLPWriter)) *OTLPWriter {
+	return NewOTLPWriter(append([]func(*OTLPWriter){func(config *OTLPWriter) {
+		config.Endpoint = c.server.URL + "/v1/logs"
+		config.FlushInterval = time.Hour
+		config.Level = log.DebugSev
+	}}, configure...)...)
+}
+
+func Test_OTel_Writer_Flush(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c, func(config *OTLPWriter) {
+		config.Headers = map[string]string{"api-key": "secret"}
+	})
+	defer writer.Close()
+
+	ctx := gcontext.AddRequestFields(context.Background(), gcontext.RequestScopedFields{
+		TraceID:             "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		CustomerAggregateID: "hooli",
+		UserAggregateID:     "UserAggregateID-123",
+	})
+	logger := log.NewFromCtxWithCustomerWriter(ctx, writer)
+
+	json := logger.Event("otel").Fields(log.Fields{
+		"string_key": "a string",
+		"int_key":    123,
+	}).Info("hello from OTel")
+
+	assert.Contains(t, json, "\"event\":\"otel\"")
+	assert.Contains(t, json, "hello from OTel")
+	assert.Empty(t, c.records())
+
+	err := writer.Flush(context.Background())
+	assert.Nil(t, err)
+
+	records := c.records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, "hello from OTel", *records[0].Body.StringValue)
+	assert.Equal(t, "INFO", records[0].SeverityText)
+	assert.Equal(t, 9, records[0].SeverityNumber)
+	assert.Equal(t, "5759e988bd862e3fe1be46a994272793", records[0].TraceID)
+	assert.Equal(t, "53995c3f42cd8ad8", records[0].SpanID)
+	assert.NotEmpty(t, records[0].TimeUnixNano)
+
+	assert.Equal(t, "secret", c.headers[0].Get("api-key"))
+	assert.Equal(t, "application/json", c.headers[0].Get("Content-Type"))
+}
+
+func Test_OTel_Writer_BatchSize(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c, func(config *OTLPWriter) {
+		config.BatchSize = 2
+	})
+	defer writer.Close()
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Info("first")
+	logger.Info("second")
+
+	c.wait(t)
+	assert.Len(t, c.records(), 2)
+
+	logger.Info("third")
+	assert.Nil(t, writer.Flush(context.Background()))
+	assert.Len(t, c.records(), 3)
+}
+
+func Test_OTel_Writer_FlushInterval(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c, func(config *OTLPWriter) {
+		config.FlushInterval = 10 * time.Millisecond
+	})
+	defer writer.Close()
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Warn("interval")
+
+	c.wait(t)
+	records := c.records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, 13, records[0].SeverityNumber)
+}
+
+func Test_OTel_Writer_Close(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c)
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Error("closing", errors.New("something went wrong"))
+
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, writer.Close())
+
+	records := c.records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, 17, records[0].SeverityNumber)
+}
+
+func Test_OTel_Writer_CollectorError(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+	c.status = http.StatusBadRequest
+
+	writer := newTestWriter(c)
+	defer writer.Close()
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Info("rejected")
+
+	err := writer.Flush(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "400")
+
+	// the batch is dropped
+	assert.Nil(t, writer.Flush(context.Background()))
+}
+
+func Test_OTel_Writer_MaxQueueSize(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c, func(config *OTLPWriter) {
+		config.MaxQueueSize = 2
+	})
+	defer writer.Close()
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Info("first")
+	logger.Info("second")
+	logger.Info("dropped")
+	assert.Equal(t, uint64(1), writer.Dropped())
+
+	assert.Nil(t, writer.Flush(context.Background()))
+	records := c.records()
+	assert.Len(t, records, 2)
+	assert.Equal(t, "second", *records[1].Body.StringValue)
+}
+
+func Test_OTel_Writer_FlushReturns(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c, func(config *OTLPWriter) {
+		config.BatchSize = 1
+	})
+	defer writer.Close()
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+
+	// an entry is written during every export, so there is always one waiting
+	handler := c.server.Config.Handler
+	c.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Info("during_export")
+		handler.ServeHTTP(w, r)
+	})
+	logger.Info("before_flush")
+
+	assert.Nil(t, writer.Flush(context.Background()))
+	records := c.records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, "before_flush", *records[0].Body.StringValue)
+}
+
+func Test_OTel_Writer_Serialize(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c)
+	defer writer.Close()
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Event("serialize").Fields(log.Fields{
+		"cause":    errors.New("connection refused"),
+		"duration": 1500 * time.Millisecond,
+		"ids":      []int{1, 2},
+	}).Info("hello")
+	assert.Nil(t, writer.Flush(context.Background()))
+
+	records := c.records()
+	assert.Len(t, records, 1)
+	properties := map[string]anyValue{}
+	for _, kv := range records[0].Attributes {
+		if kv.Key == log.Properties {
+			for _, property := range kv.Value.KvlistValue.Values {
+				properties[property.Key] = property.Value
+			}
+		}
+	}
+	assert.Equal(t, "connection refused", *properties["cause"].StringValue)
+	assert.Equal(t, "P1.5S", *properties["duration"].StringValue)
+	assert.Equal(t, "2", *properties["ids"].ArrayValue.Values[1].IntValue)
+}
+
+func Test_OTel_Writer_IsEnabled(t *testing.T) {
+	c := newCollector()
+	defer c.server.Close()
+
+	writer := newTestWriter(c, func(config *OTLPWriter) {
+		config.Level = log.ErrorSev
+	})
+	defer writer.Close()
+
+	assert.False(t, writer.IsEnabled(log.DebugSev))
+	assert.False(t, writer.IsEnabled(log.InfoSev))
+	assert.False(t, writer.IsEnabled(log.WarnSev))
+	assert.True(t, writer.IsEnabled(log.ErrorSev))
+	assert.True(t, writer.IsEnabled(log.FatalSev))
+
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), writer)
+	logger.Info("not exported")
+	assert.Nil(t, writer.Flush(context.Background()))
+	assert.Empty(t, c.records())
+}
+
+func Test_OTel_ParseHeaders(t *testing.T) {
+	headers := parseHeaders("api-key=secret, tenant = hooli%20inc,bad,=empty")
+	assert.Equal(t, map[string]string{"api-key": "secret", "tenant": "hooli inc"}, headers)
+	assert.Empty(t, parseHeaders(""))
+}